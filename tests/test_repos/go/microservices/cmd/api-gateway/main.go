@@ -5,28 +5,58 @@ import (
 	"github.com/greenfuze/go-microservices/internal/common/config"
 	"github.com/greenfuze/go-microservices/internal/common/http"
 	"github.com/greenfuze/go-microservices/internal/common/logger"
+	"github.com/greenfuze/go-microservices/internal/common/metrics"
+)
+
+// Build information, set with -ldflags "-X main.version=... -X main.commit=... -X main.date=..."
+var (
+	version = "dev"
+	commit  = "none"
+	date    = "unknown"
 )
 
 func main() {
 	logger.Info("Starting API Gateway")
+	defer logger.Sync()
+	metrics.SetServiceName("api-gateway")
+	metrics.RecordBuildInfo(version, commit, date)
 
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		logger.Error("Failed to load config", logger.GetLogger().Sugar().Fields("error", err)...)
+		logger.Error("Failed to load config", logger.Fields("error", err)...)
+		return
+	}
+	if err := cfg.Gateway.Validate(); err != nil {
+		logger.Error("Invalid gateway configuration", logger.Fields("error", err)...)
 		return
 	}
 
-	router := http.SetupRouter()
+	router, err := http.SetupRouter()
+	if err != nil {
+		logger.Error("Failed to set up router", logger.Fields("error", err)...)
+		return
+	}
+	http.RegisterHealthEndpoints(router)
+	metrics.RegisterMetricsEndpoint(router)
+	http.RegisterAdminEndpoints(router, cfg.Server.AdminToken)
 
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{"status": "ok"})
 	})
 
+	for prefix, target := range cfg.Gateway.Backends {
+		proxy := http.ProxyTo(target)
+		router.Any(prefix, proxy)
+		router.Any(prefix+"/*path", proxy)
+	}
+
 	port := cfg.Server.Port
 	if port == "" {
 		port = "8080"
 	}
 
 	logger.Info("API Gateway listening")
-	router.Run(":" + port)
+	if err := http.RunGraceful(router, ":"+port, http.DefaultShutdownTimeout); err != nil {
+		logger.Error("HTTP server failed", logger.Fields("error", err)...)
+	}
 }
@@ -5,24 +5,42 @@ import (
 	"github.com/greenfuze/go-microservices/internal/common/config"
 	"github.com/greenfuze/go-microservices/internal/common/http"
 	"github.com/greenfuze/go-microservices/internal/common/logger"
+	"github.com/greenfuze/go-microservices/internal/common/metrics"
 	"github.com/greenfuze/go-microservices/pkg/auth"
 	"github.com/google/uuid"
 )
 
+// Build information, set with -ldflags "-X main.version=... -X main.commit=... -X main.date=..."
+var (
+	version = "dev"
+	commit  = "none"
+	date    = "unknown"
+)
+
 func main() {
 	logger.Info("Starting Auth Service")
+	defer logger.Sync()
+	metrics.SetServiceName("auth-service")
+	metrics.RecordBuildInfo(version, commit, date)
 
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		logger.Error("Failed to load config", logger.GetLogger().Sugar().Fields("error", err)...)
+		logger.Error("Failed to load config", logger.Fields("error", err)...)
 		return
 	}
 
-	router := http.SetupRouter()
+	router, err := http.SetupRouter()
+	if err != nil {
+		logger.Error("Failed to set up router", logger.Fields("error", err)...)
+		return
+	}
+	http.RegisterHealthEndpoints(router)
+	metrics.RegisterMetricsEndpoint(router)
+	http.RegisterAdminEndpoints(router, cfg.Server.AdminToken)
 
 	router.POST("/auth/login", func(c *gin.Context) {
 		userID := uuid.New()
-		token, err := auth.GenerateToken(userID, "secret-key")
+		token, err := auth.GenerateTokenFromConfig(userID)
 		if err != nil {
 			c.JSON(500, gin.H{"error": "Failed to generate token"})
 			return
@@ -36,5 +54,7 @@ func main() {
 	}
 
 	logger.Info("Auth Service listening")
-	router.Run(":" + port)
+	if err := http.RunGraceful(router, ":"+port, http.DefaultShutdownTimeout); err != nil {
+		logger.Error("HTTP server failed", logger.Fields("error", err)...)
+	}
 }
@@ -5,24 +5,55 @@ import (
 	"github.com/greenfuze/go-microservices/internal/common/config"
 	"github.com/greenfuze/go-microservices/internal/common/http"
 	"github.com/greenfuze/go-microservices/internal/common/logger"
+	"github.com/greenfuze/go-microservices/internal/common/metrics"
 	"github.com/greenfuze/go-microservices/internal/common/messaging"
 )
 
+// Build information, set with -ldflags "-X main.version=... -X main.commit=... -X main.date=..."
+var (
+	version = "dev"
+	commit  = "none"
+	date    = "unknown"
+)
+
 func main() {
 	logger.Info("Starting Notification Service")
+	defer logger.Sync()
+	metrics.SetServiceName("notification-service")
+	metrics.RecordBuildInfo(version, commit, date)
 
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		logger.Error("Failed to load config", logger.GetLogger().Sugar().Fields("error", err)...)
+		logger.Error("Failed to load config", logger.Fields("error", err)...)
 		return
 	}
 
 	_, err = messaging.Connect()
 	if err != nil {
-		logger.Error("Failed to connect to messaging", logger.GetLogger().Sugar().Fields("error", err)...)
+		logger.Error("Failed to connect to messaging", logger.Fields("error", err)...)
+	} else {
+		// Subscriptions are drained first so they stop receiving but finish buffered messages,
+		// then the connection drain waits for them and closes
+		subscriptions := messaging.NewSubscriptionManager()
+		messaging.SetSubscriptionManager(subscriptions)
+		defer func() {
+			if err := subscriptions.DrainAll(); err != nil {
+				logger.Error("Failed to drain subscriptions", logger.Fields("error", err)...)
+			}
+			if err := messaging.Drain(http.DefaultShutdownTimeout); err != nil {
+				logger.Error("Failed to drain messaging", logger.Fields("error", err)...)
+			}
+		}()
 	}
 
-	router := http.SetupRouter()
+	router, err := http.SetupRouter()
+	if err != nil {
+		logger.Error("Failed to set up router", logger.Fields("error", err)...)
+		return
+	}
+	http.RegisterHealthEndpoints(router)
+	metrics.RegisterMetricsEndpoint(router)
+	http.RegisterAdminEndpoints(router, cfg.Server.AdminToken)
 
 	router.POST("/notifications", func(c *gin.Context) {
 		c.JSON(200, gin.H{"message": "Notification sent"})
@@ -34,5 +65,7 @@ func main() {
 	}
 
 	logger.Info("Notification Service listening")
-	router.Run(":" + port)
+	if err := http.RunGraceful(router, ":"+port, http.DefaultShutdownTimeout); err != nil {
+		logger.Error("HTTP server failed", logger.Fields("error", err)...)
+	}
 }
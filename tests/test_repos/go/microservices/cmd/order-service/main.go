@@ -7,14 +7,26 @@ import (
 	"github.com/greenfuze/go-microservices/internal/common/database"
 	"github.com/greenfuze/go-microservices/internal/common/http"
 	"github.com/greenfuze/go-microservices/internal/common/logger"
+	"github.com/greenfuze/go-microservices/internal/common/metrics"
 	"github.com/greenfuze/go-microservices/internal/common/messaging"
 	"github.com/greenfuze/go-microservices/pkg/models"
 	"github.com/google/uuid"
 	"context"
+	"time"
+)
+
+// Build information, set with -ldflags "-X main.version=... -X main.commit=... -X main.date=..."
+var (
+	version = "dev"
+	commit  = "none"
+	date    = "unknown"
 )
 
 func main() {
 	logger.Info("Starting Order Service")
+	defer logger.Sync()
+	metrics.SetServiceName("order-service")
+	metrics.RecordBuildInfo(version, commit, date)
 
 	cfg, err := config.LoadConfig()
 	if err != nil {
@@ -22,7 +34,9 @@ func main() {
 		return
 	}
 
-	_, err = database.Connect()
+	dbCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	_, err = database.ConnectContext(dbCtx)
+	cancel()
 	if err != nil {
 		logger.Error("Failed to connect to database")
 	}
@@ -30,6 +44,19 @@ func main() {
 	_, err = messaging.Connect()
 	if err != nil {
 		logger.Error("Failed to connect to messaging")
+	} else {
+		// Subscriptions are drained first so they stop receiving but finish buffered messages,
+		// then the connection drain waits for them and closes
+		subscriptions := messaging.NewSubscriptionManager()
+		messaging.SetSubscriptionManager(subscriptions)
+		defer func() {
+			if err := subscriptions.DrainAll(); err != nil {
+				logger.Error("Failed to drain subscriptions", logger.Fields("error", err)...)
+			}
+			if err := messaging.Drain(http.DefaultShutdownTimeout); err != nil {
+				logger.Error("Failed to drain messaging", logger.Fields("error", err)...)
+			}
+		}()
 	}
 
 	_, err = cache.Connect(context.Background())
@@ -37,7 +64,14 @@ func main() {
 		logger.Error("Failed to connect to cache")
 	}
 
-	router := http.SetupRouter()
+	router, err := http.SetupRouter()
+	if err != nil {
+		logger.Error("Failed to set up router", logger.Fields("error", err)...)
+		return
+	}
+	http.RegisterHealthEndpoints(router, database.HealthCheck, cache.HealthCheck)
+	metrics.RegisterMetricsEndpoint(router)
+	http.RegisterAdminEndpoints(router, cfg.Server.AdminToken)
 
 	router.POST("/orders", func(c *gin.Context) {
 		order := models.Order{
@@ -55,5 +89,7 @@ func main() {
 	}
 
 	logger.Info("Order Service listening")
-	router.Run(":" + port)
+	if err := http.RunGraceful(router, ":"+port, http.DefaultShutdownTimeout); err != nil {
+		logger.Error("HTTP server failed", logger.Fields("error", err)...)
+	}
 }
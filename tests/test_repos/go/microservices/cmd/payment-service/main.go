@@ -11,24 +11,40 @@ import (
 	"github.com/google/uuid"
 )
 
+// Build information, set with -ldflags "-X main.version=... -X main.commit=... -X main.date=..."
+var (
+	version = "dev"
+	commit  = "none"
+	date    = "unknown"
+)
+
 func main() {
 	logger.Info("Starting Payment Service")
+	defer logger.Sync()
+	metrics.SetServiceName("payment-service")
+	metrics.RecordBuildInfo(version, commit, date)
 
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		logger.Error("Failed to load config", logger.GetLogger().Sugar().Fields("error", err)...)
+		logger.Error("Failed to load config", logger.Fields("error", err)...)
 		return
 	}
 
 	_, err = database.Connect()
 	if err != nil {
-		logger.Error("Failed to connect to database", logger.GetLogger().Sugar().Fields("error", err)...)
+		logger.Error("Failed to connect to database", logger.Fields("error", err)...)
 	}
 
-	router := http.SetupRouter()
+	router, err := http.SetupRouter()
+	if err != nil {
+		logger.Error("Failed to set up router", logger.Fields("error", err)...)
+		return
+	}
+	http.RegisterHealthEndpoints(router, database.HealthCheck)
+	metrics.RegisterMetricsEndpoint(router)
+	http.RegisterAdminEndpoints(router, cfg.Server.AdminToken)
 
 	router.POST("/payments", func(c *gin.Context) {
-		metrics.RecordRequest("POST", "/payments")
 		payment := models.Payment{
 			ID:      uuid.New(),
 			OrderID: uuid.New(),
@@ -44,5 +60,7 @@ func main() {
 	}
 
 	logger.Info("Payment Service listening")
-	router.Run(":" + port)
+	if err := http.RunGraceful(router, ":"+port, http.DefaultShutdownTimeout); err != nil {
+		logger.Error("HTTP server failed", logger.Fields("error", err)...)
+	}
 }
@@ -6,35 +6,53 @@ import (
 	"github.com/greenfuze/go-microservices/internal/common/database"
 	"github.com/greenfuze/go-microservices/internal/common/http"
 	"github.com/greenfuze/go-microservices/internal/common/logger"
+	"github.com/greenfuze/go-microservices/internal/common/metrics"
 	"github.com/greenfuze/go-microservices/internal/common/utils"
 	"github.com/greenfuze/go-microservices/pkg/models"
 	"github.com/google/uuid"
 )
 
+// Build information, set with -ldflags "-X main.version=... -X main.commit=... -X main.date=..."
+var (
+	version = "dev"
+	commit  = "none"
+	date    = "unknown"
+)
+
 func main() {
 	logger.Info("Starting User Service")
+	defer logger.Sync()
+	metrics.SetServiceName("user-service")
+	metrics.RecordBuildInfo(version, commit, date)
 
 	// Initialize Java JVM for text formatting utilities
 	// Classpath includes both scala-utils.jar (Scala) and textutils.jar (Java that depends on Scala)
 	err := utils.InitJava("internal/common/utils/scalautils.jar:internal/common/utils/textutils.jar")
 	if err != nil {
-		logger.Error("Failed to initialize Java", logger.GetLogger().Sugar().Fields("error", err)...)
+		logger.Error("Failed to initialize Java", logger.Fields("error", err)...)
 		// Continue anyway - Java is optional
 	}
 	defer utils.CleanupJava()
 
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		logger.Error("Failed to load config", logger.GetLogger().Sugar().Fields("error", err)...)
+		logger.Error("Failed to load config", logger.Fields("error", err)...)
 		return
 	}
 
 	_, err = database.Connect()
 	if err != nil {
-		logger.Error("Failed to connect to database", logger.GetLogger().Sugar().Fields("error", err)...)
+		logger.Error("Failed to connect to database", logger.Fields("error", err)...)
 	}
 
-	router := http.SetupRouter()
+	router, err := http.SetupRouter()
+	if err != nil {
+		logger.Error("Failed to set up router", logger.Fields("error", err)...)
+		return
+	}
+	http.RegisterHealthEndpoints(router, database.HealthCheck)
+	metrics.RegisterMetricsEndpoint(router)
+	http.RegisterAdminEndpoints(router, cfg.Server.AdminToken)
 
 	router.GET("/users/:id", func(c *gin.Context) {
 		id := c.Param("id")
@@ -61,5 +79,7 @@ func main() {
 	}
 
 	logger.Info("User Service listening")
-	router.Run(":" + port)
+	if err := http.RunGraceful(router, ":"+port, http.DefaultShutdownTimeout); err != nil {
+		logger.Error("HTTP server failed", logger.Fields("error", err)...)
+	}
 }
@@ -2,43 +2,518 @@ package cache
 
 import (
 	"context"
-	"github.com/greenfuze/go-microservices/internal/common/database"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/greenfuze/go-microservices/internal/common/config"
 	"github.com/greenfuze/go-microservices/internal/common/errors"
+	"github.com/greenfuze/go-microservices/internal/common/logger"
 	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
-var rdb *redis.Client
+const (
+	// defaultHost is used when no Redis host is configured
+	defaultHost = "localhost"
+	// defaultPort is used when the configured Redis port is zero
+	defaultPort = 6379
+)
 
-// Connect establishes a Redis connection
-func Connect(ctx context.Context) (*redis.Client, error) {
-	rdb = redis.NewClient(&redis.Options{
-		Addr: "localhost:6379",
-	})
+// NoExpiration stores a key without a TTL when passed to SetWithTTL
+const NoExpiration time.Duration = 0
+
+// scanBatchSize is the SCAN COUNT hint and the number of keys deleted per pipeline
+const scanBatchSize = 100
+
+// ErrNotConnected is returned when the cache is used before Connect
+var ErrNotConnected = errors.NewAppError("CACHE_NOT_CONNECTED", "Cache not connected", nil)
+
+// ErrCacheMiss is returned by GetObject when the key is not in cache
+var ErrCacheMiss = errors.NewAppError("CACHE_MISS", "Key not found in cache", nil)
+
+// DefaultTTL is the expiration Set applies to every key
+var DefaultTTL = time.Hour
+
+// DefaultOperationTimeout bounds Get and Set calls whose context has no deadline
+// It only interrupts a stalled server when the client has ContextTimeoutEnabled, which
+// Connect sets; a zero value disables the default timeout
+var DefaultOperationTimeout = 2 * time.Second
+
+var rdb redis.UniversalClient
+
+// Connect establishes a Redis connection using the loaded configuration
+// When Redis.ClusterMode is set a cluster client is created for Redis.Addrs instead
+func Connect(ctx context.Context) (redis.UniversalClient, error) {
+	cfg := config.GetConfig()
+	if cfg != nil && cfg.Redis.ClusterMode {
+		return connectClient(ctx, redis.NewClusterClient(buildClusterOptions(cfg)))
+	}
 
-	if err := rdb.Ping(ctx).Err(); err != nil {
+	client, err := ConnectWithOptions(ctx, buildOptions(cfg))
+	if err != nil {
 		return nil, err
 	}
+	return client, nil
+}
+
+// ConnectWithOptions establishes a single-node Redis connection with full control over the client options
+func ConnectWithOptions(ctx context.Context, opts *redis.Options) (*redis.Client, error) {
+	client := redis.NewClient(opts)
+	if _, err := connectClient(ctx, client); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
 
-	return rdb, nil
+// connectClient pings client and only then makes it the package client, closing the previous one
+// On failure client is closed and the previous package client is kept
+func connectClient(ctx context.Context, client redis.UniversalClient) (redis.UniversalClient, error) {
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	previous := rdb
+	rdb = client
+	if previous != nil {
+		previous.Close()
+	}
+	return client, nil
 }
 
-// GetClient returns the Redis client
-func GetClient() *redis.Client {
+// buildOptions builds the Redis client options from configuration, falling back to
+// localhost:6379 when config is not loaded
+// Zero pool settings, and every pool setting in the localhost fallback, keep go-redis defaults
+func buildOptions(cfg *config.Config) *redis.Options {
+	if cfg == nil {
+		logger.GetLogger().Warn("Config not loaded, connecting to Redis on " + defaultHost)
+		return &redis.Options{
+			Addr:                  net.JoinHostPort(defaultHost, strconv.Itoa(defaultPort)),
+			ContextTimeoutEnabled: true,
+		}
+	}
+
+	host := cfg.Redis.Host
+	if host == "" {
+		host = defaultHost
+	}
+	port := cfg.Redis.Port
+	if port == 0 {
+		port = defaultPort
+	}
+	return &redis.Options{
+		Addr:         net.JoinHostPort(host, strconv.Itoa(port)),
+		Password:     cfg.Redis.Password,
+		DB:           cfg.Redis.DB,
+		PoolSize:     cfg.Redis.PoolSize,
+		MinIdleConns: cfg.Redis.MinIdleConns,
+		DialTimeout:  cfg.Redis.DialTimeout,
+
+		ContextTimeoutEnabled: true,
+	}
+}
+
+// buildClusterOptions builds the Redis cluster client options from configuration
+// When no Addrs are configured the single Host and Port are used as the seed node
+func buildClusterOptions(cfg *config.Config) *redis.ClusterOptions {
+	addrs := cfg.Redis.Addrs
+	if len(addrs) == 0 {
+		addrs = []string{buildOptions(cfg).Addr}
+	}
+	return &redis.ClusterOptions{
+		Addrs:        addrs,
+		Password:     cfg.Redis.Password,
+		PoolSize:     cfg.Redis.PoolSize,
+		MinIdleConns: cfg.Redis.MinIdleConns,
+		DialTimeout:  cfg.Redis.DialTimeout,
+
+		ContextTimeoutEnabled: true,
+	}
+}
+
+// GetClient returns the Redis client, which is a cluster client in cluster mode
+func GetClient() redis.UniversalClient {
 	return rdb
 }
 
-// Set stores a value in cache
+// HealthCheck pings the current Redis client, for use by readiness probes
+func HealthCheck(ctx context.Context) error {
+	if rdb == nil {
+		return ErrNotConnected
+	}
+	return rdb.Ping(ctx).Err()
+}
+
+// Set stores a value in cache with DefaultTTL
 func Set(ctx context.Context, key string, value interface{}) error {
+	return SetWithTTL(ctx, key, value, DefaultTTL)
+}
+
+// SetWithTTL stores a value in cache that expires after ttl; pass NoExpiration to keep it forever
+func SetWithTTL(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
 	if rdb == nil {
-		return errors.NewAppError("CACHE_NOT_CONNECTED", "Cache not connected", nil)
+		return ErrNotConnected
 	}
-	return rdb.Set(ctx, key, value, 0).Err()
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
+	return rdb.Set(ctx, key, value, ttl).Err()
 }
 
 // Get retrieves a value from cache
 func Get(ctx context.Context, key string) (string, error) {
 	if rdb == nil {
-		return "", errors.NewAppError("CACHE_NOT_CONNECTED", "Cache not connected", nil)
+		return "", ErrNotConnected
 	}
+	ctx, cancel := withOperationTimeout(ctx)
+	defer cancel()
 	return rdb.Get(ctx, key).Result()
 }
+
+// withOperationTimeout applies DefaultOperationTimeout unless ctx already carries a deadline
+func withOperationTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok || DefaultOperationTimeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, DefaultOperationTimeout)
+}
+
+// Delete removes keys from cache; missing keys are ignored
+func Delete(ctx context.Context, keys ...string) error {
+	if rdb == nil {
+		return ErrNotConnected
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	return rdb.Del(ctx, keys...).Err()
+}
+
+// Exists reports whether key is present in cache
+func Exists(ctx context.Context, key string) (bool, error) {
+	if rdb == nil {
+		return false, ErrNotConnected
+	}
+	n, err := rdb.Exists(ctx, key).Result()
+	return n > 0, err
+}
+
+// SetObject stores v in cache as JSON with the given TTL
+func SetObject(ctx context.Context, key string, v interface{}, ttl time.Duration) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return errors.NewAppError("CACHE_ENCODE_FAILED", "Failed to encode cache value", err)
+	}
+	return SetWithTTL(ctx, key, data, ttl)
+}
+
+// GetObject retrieves a JSON value from cache and unmarshals it into dest
+// It returns ErrCacheMiss when the key does not exist
+func GetObject(ctx context.Context, key string, dest interface{}) error {
+	data, err := Get(ctx, key)
+	if err == redis.Nil {
+		return ErrCacheMiss
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(data), dest); err != nil {
+		return errors.NewAppError("CACHE_DECODE_FAILED", "Failed to decode cache value", err)
+	}
+	return nil
+}
+
+// GetOrSet returns the cached value for key, or calls loader on a miss and caches its result with ttl
+// Loader errors are returned without caching anything
+func GetOrSet(ctx context.Context, key string, ttl time.Duration, loader func() (string, error)) (string, error) {
+	value, err := Get(ctx, key)
+	if err == nil {
+		return value, nil
+	}
+	if err != redis.Nil {
+		return "", err
+	}
+
+	value, err = loader()
+	if err != nil {
+		return "", err
+	}
+	if err := SetWithTTL(ctx, key, value, ttl); err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+// Incr atomically increments the integer stored at key, starting from 0 when it does not exist
+func Incr(ctx context.Context, key string) (int64, error) {
+	if rdb == nil {
+		return 0, ErrNotConnected
+	}
+	return rdb.Incr(ctx, key).Result()
+}
+
+// IncrBy atomically increments the integer stored at key by n
+func IncrBy(ctx context.Context, key string, n int64) (int64, error) {
+	if rdb == nil {
+		return 0, ErrNotConnected
+	}
+	return rdb.IncrBy(ctx, key, n).Result()
+}
+
+// Expire sets a TTL on an existing key and reports whether the key existed
+func Expire(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	if rdb == nil {
+		return false, ErrNotConnected
+	}
+	return rdb.Expire(ctx, key, ttl).Result()
+}
+
+// MGet retrieves several keys in one round trip
+// Values are returned in key order, with an empty string for each missing key
+func MGet(ctx context.Context, keys ...string) ([]string, error) {
+	if rdb == nil {
+		return nil, ErrNotConnected
+	}
+	if len(keys) == 0 {
+		return []string{}, nil
+	}
+
+	values, err := rdb.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]string, len(values))
+	for i, v := range values {
+		if s, ok := v.(string); ok {
+			result[i] = s
+		}
+	}
+	return result, nil
+}
+
+// Batch queues cache commands and sends them to Redis in a single round trip on Exec
+type Batch struct {
+	ops []func(ctx context.Context, pipe redis.Pipeliner)
+}
+
+// BatchValue holds the result of a Get queued on a Batch, available after Exec
+type BatchValue struct {
+	cmd *redis.StringCmd
+}
+
+// Pipeline starts a new Batch
+func Pipeline() *Batch {
+	return &Batch{}
+}
+
+// Set queues storing value at key with the given TTL
+func (b *Batch) Set(key string, value interface{}, ttl time.Duration) *Batch {
+	b.ops = append(b.ops, func(ctx context.Context, pipe redis.Pipeliner) {
+		pipe.Set(ctx, key, value, ttl)
+	})
+	return b
+}
+
+// Get queues reading key and returns a BatchValue that is populated by Exec
+func (b *Batch) Get(key string) *BatchValue {
+	value := &BatchValue{}
+	b.ops = append(b.ops, func(ctx context.Context, pipe redis.Pipeliner) {
+		value.cmd = pipe.Get(ctx, key)
+	})
+	return value
+}
+
+// Del queues removing keys
+func (b *Batch) Del(keys ...string) *Batch {
+	b.ops = append(b.ops, func(ctx context.Context, pipe redis.Pipeliner) {
+		pipe.Del(ctx, keys...)
+	})
+	return b
+}
+
+// Exec sends all queued commands in one round trip
+// Missing keys are not an error here; they surface as redis.Nil from the BatchValue
+func (b *Batch) Exec(ctx context.Context) error {
+	if rdb == nil {
+		return ErrNotConnected
+	}
+	_, err := rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, op := range b.ops {
+			op(ctx, pipe)
+		}
+		return nil
+	})
+	if err == redis.Nil {
+		return nil
+	}
+	return err
+}
+
+// Result returns the value read by the queued Get
+func (v *BatchValue) Result() (string, error) {
+	if v.cmd == nil {
+		return "", errors.NewAppError("CACHE_BATCH_NOT_EXECUTED", "Batch has not been executed", nil)
+	}
+	return v.cmd.Result()
+}
+
+// PublishMessage publishes payload to a Redis pub/sub channel
+func PublishMessage(ctx context.Context, channel, payload string) error {
+	if rdb == nil {
+		return ErrNotConnected
+	}
+	return rdb.Publish(ctx, channel, payload).Err()
+}
+
+// SubscribeChannel subscribes to a Redis pub/sub channel and returns its payloads plus a cleanup
+// function that unsubscribes, stops the pumping goroutine and closes the returned channel
+func SubscribeChannel(ctx context.Context, channel string) (<-chan string, func(), error) {
+	if rdb == nil {
+		return nil, nil, ErrNotConnected
+	}
+
+	pubsub := rdb.Subscribe(ctx, channel)
+	// Wait for the subscription confirmation so messages published after we return are not missed
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, nil, err
+	}
+
+	out := make(chan string)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		defer close(out)
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-done:
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				select {
+				case out <- msg.Payload:
+				case <-done:
+					return
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	cleanup := func() {
+		once.Do(func() {
+			close(done)
+			pubsub.Close()
+			<-stopped
+		})
+	}
+	return out, cleanup, nil
+}
+
+// SetNX stores value at key only if the key does not exist and reports whether it was set
+func SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	if rdb == nil {
+		return false, ErrNotConnected
+	}
+	return rdb.SetNX(ctx, key, value, ttl).Result()
+}
+
+// releaseLockScript deletes the lock key only if it still holds our token
+var releaseLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// AcquireLock takes a distributed lock on key that expires after ttl
+// ok is false when another holder owns the lock; release frees it only while
+// this caller's token is still stored, so an expired lock taken over by someone else is left alone
+func AcquireLock(ctx context.Context, key string, ttl time.Duration) (release func(), ok bool, err error) {
+	tokenBytes := make([]byte, 16)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return nil, false, err
+	}
+	token := hex.EncodeToString(tokenBytes)
+
+	ok, err = SetNX(ctx, key, token, ttl)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+
+	release = func() {
+		releaseCtx, cancel := withOperationTimeout(context.Background())
+		defer cancel()
+		if err := releaseLockScript.Run(releaseCtx, rdb, []string{key}, token).Err(); err != nil {
+			logger.Error("Failed to release cache lock", zap.String("key", key), zap.Error(err))
+		}
+	}
+	return release, true, nil
+}
+
+// DeleteByPattern removes every key matching a glob pattern such as "user:*:profile" and
+// returns how many were removed
+// It walks the keyspace with SCAN rather than KEYS so Redis is never blocked, but the cost
+// is still O(keyspace); in cluster mode every master is scanned
+func DeleteByPattern(ctx context.Context, pattern string) (int, error) {
+	if rdb == nil {
+		return 0, ErrNotConnected
+	}
+
+	if cluster, ok := rdb.(*redis.ClusterClient); ok {
+		var total atomic.Int64
+		err := cluster.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+			n, err := deleteByPattern(ctx, node, pattern)
+			total.Add(int64(n))
+			return err
+		})
+		return int(total.Load()), err
+	}
+	return deleteByPattern(ctx, rdb, pattern)
+}
+
+func deleteByPattern(ctx context.Context, client redis.UniversalClient, pattern string) (int, error) {
+	deleted := 0
+	batch := make([]string, 0, scanBatchSize)
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		cmds, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, key := range batch {
+				pipe.Del(ctx, key)
+			}
+			return nil
+		})
+		for _, cmd := range cmds {
+			if del, ok := cmd.(*redis.IntCmd); ok {
+				deleted += int(del.Val())
+			}
+		}
+		batch = batch[:0]
+		return err
+	}
+
+	iter := client.Scan(ctx, 0, pattern, scanBatchSize).Iterator()
+	for iter.Next(ctx) {
+		batch = append(batch, iter.Val())
+		if len(batch) == scanBatchSize {
+			if err := flush(); err != nil {
+				return deleted, err
+			}
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return deleted, err
+	}
+	return deleted, flush()
+}
@@ -1,9 +1,560 @@
 package cache
 
-import "testing"
+import (
+	"context"
+	stderrors "errors"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/greenfuze/go-microservices/internal/common/config"
+	"github.com/redis/go-redis/v9"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
 
 func TestGetClient(t *testing.T) {
 	client := GetClient()
 	// In test environment, client might be nil if Connect wasn't called
 	_ = client
 }
+
+// setupMiniredis points the package client at an in-memory Redis for the duration of the test
+func setupMiniredis(t *testing.T) *miniredis.Miniredis {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rdb = redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		rdb.Close()
+		rdb = nil
+	})
+	return mr
+}
+
+func TestSetWithTTLExpires(t *testing.T) {
+	mr := setupMiniredis(t)
+	ctx := context.Background()
+
+	if err := SetWithTTL(ctx, "session", "abc", time.Minute); err != nil {
+		t.Fatalf("SetWithTTL failed: %v", err)
+	}
+	if value, err := Get(ctx, "session"); err != nil || value != "abc" {
+		t.Fatalf("Get returned %q, %v", value, err)
+	}
+
+	mr.FastForward(2 * time.Minute)
+	if _, err := Get(ctx, "session"); err != redis.Nil {
+		t.Errorf("expected key to expire, Get returned %v", err)
+	}
+}
+
+func TestSetUsesDefaultTTL(t *testing.T) {
+	mr := setupMiniredis(t)
+	ctx := context.Background()
+
+	if err := Set(ctx, "key", "value"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if ttl := mr.TTL("key"); ttl != DefaultTTL {
+		t.Errorf("Set applied TTL %v, expected %v", ttl, DefaultTTL)
+	}
+
+	if err := SetWithTTL(ctx, "forever", "value", NoExpiration); err != nil {
+		t.Fatalf("SetWithTTL failed: %v", err)
+	}
+	if ttl := mr.TTL("forever"); ttl != 0 {
+		t.Errorf("NoExpiration applied TTL %v", ttl)
+	}
+}
+
+func TestDeleteAndExists(t *testing.T) {
+	setupMiniredis(t)
+	ctx := context.Background()
+
+	if exists, err := Exists(ctx, "user:1"); err != nil || exists {
+		t.Fatalf("Exists before Set returned %v, %v", exists, err)
+	}
+	if err := Set(ctx, "user:1", "alice"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if exists, err := Exists(ctx, "user:1"); err != nil || !exists {
+		t.Fatalf("Exists after Set returned %v, %v", exists, err)
+	}
+
+	if err := Delete(ctx, "user:1", "missing"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if exists, err := Exists(ctx, "user:1"); err != nil || exists {
+		t.Errorf("Exists after Delete returned %v, %v", exists, err)
+	}
+	if err := Delete(ctx, "missing"); err != nil {
+		t.Errorf("Delete of a missing key failed: %v", err)
+	}
+}
+
+func TestNotConnected(t *testing.T) {
+	ctx := context.Background()
+	if err := Delete(ctx, "key"); err != ErrNotConnected {
+		t.Errorf("Delete returned %v, expected ErrNotConnected", err)
+	}
+	if _, err := Exists(ctx, "key"); err != ErrNotConnected {
+		t.Errorf("Exists returned %v, expected ErrNotConnected", err)
+	}
+}
+
+func TestBuildOptions(t *testing.T) {
+	cfg := &config.Config{Redis: config.RedisConfig{Host: "redis.internal", Port: 6380, Password: "pw", DB: 2}}
+	opts := buildOptions(cfg)
+	if opts.Addr != "redis.internal:6380" {
+		t.Errorf("Addr = %q, expected redis.internal:6380", opts.Addr)
+	}
+	if opts.Password != "pw" || opts.DB != 2 {
+		t.Errorf("Password/DB not applied: %q, %d", opts.Password, opts.DB)
+	}
+
+	cfg.Redis.PoolSize = 50
+	cfg.Redis.MinIdleConns = 5
+	cfg.Redis.DialTimeout = 2 * time.Second
+	opts = buildOptions(cfg)
+	if opts.PoolSize != 50 || opts.MinIdleConns != 5 || opts.DialTimeout != 2*time.Second {
+		t.Errorf("pool settings not applied: %d, %d, %v", opts.PoolSize, opts.MinIdleConns, opts.DialTimeout)
+	}
+
+	if opts := buildOptions(&config.Config{}); opts.Addr != "localhost:6379" {
+		t.Errorf("Addr = %q, expected localhost:6379 for empty config", opts.Addr)
+	}
+	if opts := buildOptions(nil); opts.Addr != "localhost:6379" {
+		t.Errorf("Addr = %q, expected localhost:6379 without config", opts.Addr)
+	}
+}
+
+type cachedProfile struct {
+	ID    int      `json:"id"`
+	Name  string   `json:"name"`
+	Roles []string `json:"roles"`
+}
+
+func TestSetGetObject(t *testing.T) {
+	setupMiniredis(t)
+	ctx := context.Background()
+
+	profile := cachedProfile{ID: 7, Name: "alice", Roles: []string{"admin", "user"}}
+	if err := SetObject(ctx, "profile:7", profile, time.Minute); err != nil {
+		t.Fatalf("SetObject failed: %v", err)
+	}
+
+	var loaded cachedProfile
+	if err := GetObject(ctx, "profile:7", &loaded); err != nil {
+		t.Fatalf("GetObject failed: %v", err)
+	}
+	if loaded.ID != profile.ID || loaded.Name != profile.Name || len(loaded.Roles) != 2 {
+		t.Errorf("GetObject returned %+v, expected %+v", loaded, profile)
+	}
+
+	if err := GetObject(ctx, "profile:8", &loaded); err != ErrCacheMiss {
+		t.Errorf("GetObject on a missing key returned %v, expected ErrCacheMiss", err)
+	}
+}
+
+func TestGetOrSet(t *testing.T) {
+	setupMiniredis(t)
+	ctx := context.Background()
+
+	calls := 0
+	loader := func() (string, error) {
+		calls++
+		return "loaded", nil
+	}
+
+	for i := 0; i < 2; i++ {
+		value, err := GetOrSet(ctx, "report", time.Minute, loader)
+		if err != nil {
+			t.Fatalf("GetOrSet failed: %v", err)
+		}
+		if value != "loaded" {
+			t.Errorf("GetOrSet returned %q, expected loaded", value)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("loader called %d times, expected 1", calls)
+	}
+}
+
+func TestGetOrSetLoaderError(t *testing.T) {
+	setupMiniredis(t)
+	ctx := context.Background()
+
+	loaderErr := stderrors.New("backend down")
+	if _, err := GetOrSet(ctx, "report", time.Minute, func() (string, error) { return "", loaderErr }); err != loaderErr {
+		t.Errorf("GetOrSet returned %v, expected the loader error", err)
+	}
+	if exists, _ := Exists(ctx, "report"); exists {
+		t.Error("GetOrSet cached a value after a loader error")
+	}
+}
+
+func TestIncr(t *testing.T) {
+	mr := setupMiniredis(t)
+	ctx := context.Background()
+
+	for expected := int64(1); expected <= 2; expected++ {
+		n, err := Incr(ctx, "views")
+		if err != nil {
+			t.Fatalf("Incr failed: %v", err)
+		}
+		if n != expected {
+			t.Errorf("Incr returned %d, expected %d", n, expected)
+		}
+	}
+
+	if n, err := IncrBy(ctx, "views", 10); err != nil || n != 12 {
+		t.Errorf("IncrBy returned %d, %v, expected 12", n, err)
+	}
+
+	if ok, err := Expire(ctx, "views", time.Minute); err != nil || !ok {
+		t.Errorf("Expire returned %v, %v", ok, err)
+	}
+	if ttl := mr.TTL("views"); ttl != time.Minute {
+		t.Errorf("Expire applied TTL %v, expected 1m", ttl)
+	}
+	if ok, err := Expire(ctx, "missing", time.Minute); err != nil || ok {
+		t.Errorf("Expire on a missing key returned %v, %v", ok, err)
+	}
+}
+
+func TestMGet(t *testing.T) {
+	setupMiniredis(t)
+	ctx := context.Background()
+
+	Set(ctx, "a", "1")
+	Set(ctx, "c", "3")
+
+	values, err := MGet(ctx, "a", "b", "c")
+	if err != nil {
+		t.Fatalf("MGet failed: %v", err)
+	}
+	if len(values) != 3 || values[0] != "1" || values[1] != "" || values[2] != "3" {
+		t.Errorf("MGet returned %q", values)
+	}
+}
+
+func TestPipeline(t *testing.T) {
+	setupMiniredis(t)
+	ctx := context.Background()
+
+	Set(ctx, "stale", "x")
+
+	batch := Pipeline().Set("fresh", "y", time.Minute).Del("stale")
+	existing := batch.Get("fresh")
+	missing := batch.Get("missing")
+	if err := batch.Exec(ctx); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+
+	if value, err := existing.Result(); err != nil || value != "y" {
+		t.Errorf("queued Get returned %q, %v", value, err)
+	}
+	if _, err := missing.Result(); err != redis.Nil {
+		t.Errorf("queued Get of a missing key returned %v, expected redis.Nil", err)
+	}
+	if exists, _ := Exists(ctx, "stale"); exists {
+		t.Error("queued Del did not remove the key")
+	}
+}
+
+func benchmarkKeys(b *testing.B) []string {
+	mr := miniredis.RunT(b)
+	rdb = redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	b.Cleanup(func() {
+		rdb.Close()
+		rdb = nil
+	})
+
+	keys := make([]string, 100)
+	for i := range keys {
+		keys[i] = "key:" + strconv.Itoa(i)
+		mr.Set(keys[i], "value")
+	}
+	return keys
+}
+
+func BenchmarkGetSingle(b *testing.B) {
+	keys := benchmarkKeys(b)
+	ctx := context.Background()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, key := range keys {
+			if _, err := Get(ctx, key); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkMGet(b *testing.B) {
+	keys := benchmarkKeys(b)
+	ctx := context.Background()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := MGet(ctx, keys...); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestConnectWithOptions(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client, err := ConnectWithOptions(context.Background(), &redis.Options{Addr: mr.Addr(), PoolSize: 3})
+	if err != nil {
+		t.Fatalf("ConnectWithOptions failed: %v", err)
+	}
+	defer func() {
+		client.Close()
+		rdb = nil
+	}()
+
+	if GetClient() != client {
+		t.Error("ConnectWithOptions did not set the package client")
+	}
+	if client.Options().PoolSize != 3 {
+		t.Errorf("PoolSize = %d, expected 3", client.Options().PoolSize)
+	}
+}
+
+func TestConnectWithOptionsSwapsClient(t *testing.T) {
+	mr := miniredis.RunT(t)
+	first, err := ConnectWithOptions(context.Background(), &redis.Options{Addr: mr.Addr()})
+	if err != nil {
+		t.Fatalf("ConnectWithOptions failed: %v", err)
+	}
+	defer func() {
+		GetClient().Close()
+		rdb = nil
+	}()
+
+	if _, err := ConnectWithOptions(context.Background(), &redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1}); err == nil {
+		t.Fatal("ConnectWithOptions succeeded against a closed port")
+	}
+	if GetClient() != first {
+		t.Fatal("failed connect replaced the working client")
+	}
+	if err := first.Ping(context.Background()).Err(); err != nil {
+		t.Fatalf("working client unusable after a failed connect: %v", err)
+	}
+
+	second, err := ConnectWithOptions(context.Background(), &redis.Options{Addr: mr.Addr()})
+	if err != nil {
+		t.Fatalf("ConnectWithOptions failed: %v", err)
+	}
+	if GetClient() != second {
+		t.Error("ConnectWithOptions did not swap in the new client")
+	}
+	if err := first.Ping(context.Background()).Err(); !stderrors.Is(err, redis.ErrClosed) {
+		t.Errorf("previous client ping returned %v, expected it to be closed", err)
+	}
+}
+
+func TestGetDefaultOperationTimeout(t *testing.T) {
+	// A listener that accepts connections but never answers simulates a stalled Redis
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	rdb = redis.NewClient(&redis.Options{Addr: listener.Addr().String(), ContextTimeoutEnabled: true})
+	defer func() {
+		rdb.Close()
+		rdb = nil
+	}()
+
+	previous := DefaultOperationTimeout
+	DefaultOperationTimeout = 50 * time.Millisecond
+	defer func() { DefaultOperationTimeout = previous }()
+
+	start := time.Now()
+	if _, err := Get(context.Background(), "key"); err == nil {
+		t.Fatal("Get succeeded against a stalled server")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Get took %v despite a 50ms default timeout", elapsed)
+	}
+}
+
+func TestSetCanceledContext(t *testing.T) {
+	setupMiniredis(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := Set(ctx, "key", "value"); err == nil {
+		t.Error("Set succeeded with a canceled context")
+	}
+}
+
+func TestPublishSubscribeChannel(t *testing.T) {
+	setupMiniredis(t)
+	ctx := context.Background()
+
+	messages, cleanup, err := SubscribeChannel(ctx, "notifications")
+	if err != nil {
+		t.Fatalf("SubscribeChannel failed: %v", err)
+	}
+
+	if err := PublishMessage(ctx, "notifications", "order shipped"); err != nil {
+		t.Fatalf("PublishMessage failed: %v", err)
+	}
+
+	select {
+	case payload := <-messages:
+		if payload != "order shipped" {
+			t.Errorf("received %q, expected order shipped", payload)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for the published message")
+	}
+
+	cleanup()
+	if _, ok := <-messages; ok {
+		t.Error("message channel still open after cleanup")
+	}
+	cleanup()
+}
+
+func TestBuildClusterOptions(t *testing.T) {
+	cfg := &config.Config{Redis: config.RedisConfig{
+		ClusterMode: true,
+		Addrs:       []string{"redis-1:6379", "redis-2:6379"},
+		Password:    "pw",
+	}}
+	opts := buildClusterOptions(cfg)
+	if len(opts.Addrs) != 2 || opts.Addrs[0] != "redis-1:6379" || opts.Password != "pw" {
+		t.Errorf("unexpected cluster options: %+v", opts)
+	}
+
+	cfg.Redis.Addrs = nil
+	cfg.Redis.Host = "redis-seed"
+	if opts := buildClusterOptions(cfg); len(opts.Addrs) != 1 || opts.Addrs[0] != "redis-seed:6379" {
+		t.Errorf("cluster options without Addrs = %v, expected the seed host", opts.Addrs)
+	}
+}
+
+func TestConnectClusterMode(t *testing.T) {
+	mr := miniredis.RunT(t)
+	if _, err := config.LoadConfig(); err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	redisConfig := &config.GetConfig().Redis
+	previousClusterMode, previousAddrs := redisConfig.ClusterMode, redisConfig.Addrs
+	t.Cleanup(func() {
+		redisConfig.ClusterMode, redisConfig.Addrs = previousClusterMode, previousAddrs
+	})
+	redisConfig.ClusterMode = true
+	redisConfig.Addrs = []string{mr.Addr()}
+
+	client, err := Connect(context.Background())
+	if err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	defer func() {
+		client.Close()
+		rdb = nil
+	}()
+
+	if _, ok := GetClient().(*redis.ClusterClient); !ok {
+		t.Fatalf("GetClient returned %T, expected *redis.ClusterClient", GetClient())
+	}
+	if err := Set(context.Background(), "key", "value"); err != nil {
+		t.Fatalf("Set failed on the cluster client: %v", err)
+	}
+	if value, err := Get(context.Background(), "key"); err != nil || value != "value" {
+		t.Errorf("Get returned %q, %v", value, err)
+	}
+}
+
+func TestHealthCheck(t *testing.T) {
+	if err := HealthCheck(context.Background()); err != ErrNotConnected {
+		t.Errorf("HealthCheck returned %v without a connection, expected ErrNotConnected", err)
+	}
+
+	mr := setupMiniredis(t)
+	if err := HealthCheck(context.Background()); err != nil {
+		t.Errorf("HealthCheck failed while connected: %v", err)
+	}
+
+	mr.Close()
+	if err := HealthCheck(context.Background()); err == nil {
+		t.Error("HealthCheck succeeded after the server went away")
+	}
+}
+
+func TestSetNX(t *testing.T) {
+	setupMiniredis(t)
+	ctx := context.Background()
+
+	if ok, err := SetNX(ctx, "job", "a", time.Minute); err != nil || !ok {
+		t.Fatalf("first SetNX returned %v, %v", ok, err)
+	}
+	if ok, err := SetNX(ctx, "job", "b", time.Minute); err != nil || ok {
+		t.Errorf("second SetNX returned %v, %v", ok, err)
+	}
+}
+
+func TestAcquireLock(t *testing.T) {
+	mr := setupMiniredis(t)
+	ctx := context.Background()
+
+	release, ok, err := AcquireLock(ctx, "lock:order:1", time.Minute)
+	if err != nil || !ok {
+		t.Fatalf("AcquireLock returned %v, %v", ok, err)
+	}
+
+	if _, ok, err := AcquireLock(ctx, "lock:order:1", time.Minute); err != nil || ok {
+		t.Fatalf("second AcquireLock returned %v, %v while the lock was held", ok, err)
+	}
+
+	release()
+	release2, ok, err := AcquireLock(ctx, "lock:order:1", time.Minute)
+	if err != nil || !ok {
+		t.Fatalf("AcquireLock after release returned %v, %v", ok, err)
+	}
+
+	// A stale release must not free a lock now owned by another holder
+	mr.Set("lock:order:1", "someone-else")
+	release2()
+	if !mr.Exists("lock:order:1") {
+		t.Error("release removed a lock owned by another holder")
+	}
+}
+
+func TestDeleteByPattern(t *testing.T) {
+	mr := setupMiniredis(t)
+	ctx := context.Background()
+
+	for i := 0; i < 250; i++ {
+		mr.Set("user:"+strconv.Itoa(i)+":profile", "p")
+	}
+	mr.Set("user:1:settings", "s")
+	mr.Set("order:1:profile", "o")
+
+	deleted, err := DeleteByPattern(ctx, "user:*:profile")
+	if err != nil {
+		t.Fatalf("DeleteByPattern failed: %v", err)
+	}
+	if deleted != 250 {
+		t.Errorf("DeleteByPattern removed %d keys, expected 250", deleted)
+	}
+	if !mr.Exists("user:1:settings") || !mr.Exists("order:1:profile") {
+		t.Error("DeleteByPattern removed keys that do not match the pattern")
+	}
+	if keys := mr.Keys(); len(keys) != 2 {
+		t.Errorf("%d keys left, expected 2", len(keys))
+	}
+}
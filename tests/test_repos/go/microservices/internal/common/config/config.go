@@ -1,45 +1,130 @@
 package config
 
 import (
+	"errors"
 	"github.com/greenfuze/go-microservices/internal/common/logger"
 	"github.com/greenfuze/go-microservices/internal/common/validation"
 	"github.com/spf13/viper"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // Config holds application configuration
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	Redis    RedisConfig
-	NATS     NATSConfig
+	Environment string
+	Server      ServerConfig
+	Database    DatabaseConfig
+	Redis       RedisConfig
+	NATS        NATSConfig
+	JWT         JWTConfig
+	Gateway     GatewayConfig
 }
 
 // ServerConfig holds server configuration
 type ServerConfig struct {
-	Port string
-	Host string
+	Port         string
+	Host         string
+	CORSOrigins  []string
+	MaxBodyBytes int64
+	AdminToken   string
 }
 
 // DatabaseConfig holds database configuration
 type DatabaseConfig struct {
+	Driver   string
 	Host     string
 	Port     int
 	User     string
 	Password string
 	DBName   string
+	SSLMode  string
+	Pool     PoolConfig
+}
+
+// PoolConfig holds database connection pool configuration
+type PoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
 }
 
 // RedisConfig holds Redis configuration
 type RedisConfig struct {
-	Host string
-	Port int
+	Host         string
+	Port         int
+	Password     string
+	DB           int
+	PoolSize     int
+	MinIdleConns int
+	DialTimeout  time.Duration
+	ClusterMode  bool
+	Addrs        []string
 }
 
 // NATSConfig holds NATS configuration
 type NATSConfig struct {
-	URL string
+	URL       string
+	CredsFile string
+	TLSCert   string
+	TLSKey    string
+	CAFile    string
+}
+
+// JWTConfig holds JWT signing configuration
+type JWTConfig struct {
+	Secret    string
+	AccessTTL time.Duration
+}
+
+// GatewayConfig holds the api-gateway's routing configuration
+type GatewayConfig struct {
+	// Backends maps route prefixes such as "/orders" to downstream service base URLs
+	Backends map[string]string
+}
+
+// DefaultGatewayBackends route to each service on its default port on the same host; the
+// ports match the fallbacks in cmd/*/main.go, used when server.port is not configured
+var DefaultGatewayBackends = map[string]string{
+	"/auth":          "http://localhost:8081",
+	"/users":         "http://localhost:8082",
+	"/orders":        "http://localhost:8083",
+	"/payments":      "http://localhost:8084",
+	"/notifications": "http://localhost:8085",
+}
+
+// Validate checks that there is at least one backend, that every prefix starts with "/" and
+// that every target is an absolute http or https URL
+func (g GatewayConfig) Validate() error {
+	if len(g.Backends) == 0 {
+		return errors.New("gateway.backends must configure at least one backend")
+	}
+
+	prefixes := make([]string, 0, len(g.Backends))
+	for prefix := range g.Backends {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+
+	var errs []error
+	for _, prefix := range prefixes {
+		if !strings.HasPrefix(prefix, "/") {
+			errs = append(errs, errors.New("gateway backend prefix "+strconv.Quote(prefix)+" must start with /"))
+		}
+		target, err := url.Parse(g.Backends[prefix])
+		if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+			errs = append(errs, errors.New("gateway backend "+prefix+" has an invalid URL "+strconv.Quote(g.Backends[prefix])))
+		}
+	}
+	return errors.Join(errs...)
 }
 
+// DefaultEnvironment is the environment used when none is configured
+const DefaultEnvironment = "development"
+
 var appConfig *Config
 
 // LoadConfig loads configuration from file and environment
@@ -49,13 +134,24 @@ func LoadConfig() (*Config, error) {
 	viper.AddConfigPath(".")
 	viper.AddConfigPath("./configs")
 
-	viper.SetDefault("server.port", "8080")
 	viper.SetDefault("server.host", "localhost")
+	viper.SetDefault("server.admintoken", "")
+	viper.SetDefault("environment", DefaultEnvironment)
+	viper.SetDefault("database.driver", "postgres")
+	viper.SetDefault("database.sslmode", "disable")
+	viper.SetDefault("database.pool.maxopenconns", 25)
+	viper.SetDefault("database.pool.maxidleconns", 5)
+	viper.SetDefault("database.pool.connmaxlifetime", "30m")
+	viper.SetDefault("database.pool.connmaxidletime", "5m")
+	viper.SetDefault("jwt.secret", "")
+	viper.SetDefault("jwt.accessttl", "15m")
+	viper.SetDefault("gateway.backends", DefaultGatewayBackends)
 
 	if err := viper.ReadInConfig(); err != nil {
 		logger.Info("Config file not found, using defaults")
 	}
 
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 	viper.AutomaticEnv()
 
 	config := &Config{}
@@ -67,6 +163,10 @@ func LoadConfig() (*Config, error) {
 		return nil, err
 	}
 
+	if config.Environment != DefaultEnvironment && config.JWT.Secret == "" {
+		return nil, errors.New("jwt.secret must be set in the " + config.Environment + " environment")
+	}
+
 	appConfig = config
 	return config, nil
 }
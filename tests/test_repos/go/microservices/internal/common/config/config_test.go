@@ -1,6 +1,9 @@
 package config
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
 
 func TestLoadConfig(t *testing.T) {
 	cfg, err := LoadConfig()
@@ -11,3 +14,66 @@ func TestLoadConfig(t *testing.T) {
 		t.Error("LoadConfig returned nil config")
 	}
 }
+
+func TestLoadConfigJWT(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+	t.Setenv("JWT_ACCESSTTL", "30m")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.JWT.Secret != "test-secret" {
+		t.Errorf("JWT.Secret = %q, expected test-secret", cfg.JWT.Secret)
+	}
+	if cfg.JWT.AccessTTL != 30*time.Minute {
+		t.Errorf("JWT.AccessTTL = %v, expected 30m", cfg.JWT.AccessTTL)
+	}
+}
+
+func TestLoadConfigRequiresJWTSecretOutsideDefaultEnvironment(t *testing.T) {
+	t.Setenv("ENVIRONMENT", "production")
+	t.Setenv("JWT_SECRET", "")
+
+	if _, err := LoadConfig(); err == nil {
+		t.Error("LoadConfig succeeded without a JWT secret in production")
+	}
+}
+
+func TestLoadConfigGatewayBackends(t *testing.T) {
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if len(cfg.Gateway.Backends) != len(DefaultGatewayBackends) {
+		t.Fatalf("Gateway.Backends = %v, expected the defaults", cfg.Gateway.Backends)
+	}
+	for prefix, target := range DefaultGatewayBackends {
+		if cfg.Gateway.Backends[prefix] != target {
+			t.Errorf("backend %s = %q, expected %q", prefix, cfg.Gateway.Backends[prefix], target)
+		}
+	}
+	if err := cfg.Gateway.Validate(); err != nil {
+		t.Errorf("default backends failed validation: %v", err)
+	}
+}
+
+func TestGatewayConfigValidate(t *testing.T) {
+	invalid := []GatewayConfig{
+		{},
+		{Backends: map[string]string{"orders": "http://localhost:8083"}},
+		{Backends: map[string]string{"/orders": "localhost:8083"}},
+		{Backends: map[string]string{"/orders": "ftp://localhost:8083"}},
+		{Backends: map[string]string{"/orders": "http://"}},
+	}
+	for _, gateway := range invalid {
+		if err := gateway.Validate(); err == nil {
+			t.Errorf("Validate accepted %v", gateway.Backends)
+		}
+	}
+
+	valid := GatewayConfig{Backends: map[string]string{"/orders": "https://orders.internal"}}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Validate rejected %v: %v", valid.Backends, err)
+	}
+}
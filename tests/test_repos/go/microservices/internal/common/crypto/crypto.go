@@ -6,11 +6,23 @@ package crypto
 */
 import "C"
 import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
 	"encoding/hex"
 	"errors"
+	"github.com/greenfuze/go-microservices/internal/common/passwordpolicy"
 	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/pbkdf2"
+	"io"
+	"runtime"
 	"unsafe"
 )
 
@@ -26,6 +38,43 @@ func CheckPasswordHash(password, hash string) bool {
 	return err == nil
 }
 
+// CheckPasswordStrength returns an error naming the first strength rule the password fails
+// The rules are configured in the passwordpolicy package, shared with the validation package
+func CheckPasswordStrength(password string) error {
+	return passwordpolicy.Check(password)
+}
+
+// HashPasswordPeppered hashes a password with bcrypt after applying an HMAC-SHA256 server-side pepper
+// Changing the pepper invalidates every hash created with the old one, so rotating it requires
+// keeping the old pepper around to verify and rehash existing passwords
+func HashPasswordPeppered(password, pepper string) (string, error) {
+	return HashPassword(pepperPassword(password, pepper))
+}
+
+// CheckPasswordPeppered verifies a password against a hash created by HashPasswordPeppered
+func CheckPasswordPeppered(password, pepper, hash string) bool {
+	return CheckPasswordHash(pepperPassword(password, pepper), hash)
+}
+
+// pepperPassword hex-encodes the HMAC so the result stays within bcrypt's 72 byte input limit
+func pepperPassword(password, pepper string) string {
+	mac := hmac.New(sha256.New, []byte(pepper))
+	mac.Write([]byte(password))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// NeedsRehash reports whether a bcrypt hash was generated with a cost below desiredCost
+func NeedsRehash(hash string, desiredCost int) (bool, error) {
+	if desiredCost < bcrypt.MinCost || desiredCost > bcrypt.MaxCost {
+		return false, errors.New("desired bcrypt cost out of range")
+	}
+	cost, err := bcrypt.Cost([]byte(hash))
+	if err != nil {
+		return false, err
+	}
+	return cost < desiredCost, nil
+}
+
 // GenerateRandomToken generates a random token
 func GenerateRandomToken(length int) (string, error) {
 	bytes := make([]byte, length)
@@ -35,12 +84,113 @@ func GenerateRandomToken(length int) (string, error) {
 	return hex.EncodeToString(bytes), nil
 }
 
+// GenerateRandomTokenURLSafe generates a random token of length bytes encoded as unpadded base64url
+func GenerateRandomTokenURLSafe(length int) (string, error) {
+	if length <= 0 {
+		return "", errors.New("token length must be positive")
+	}
+	bytes := make([]byte, length)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
+
 // SHA256Hash computes SHA256 hash of input
 func SHA256Hash(input string) string {
 	hash := sha256.Sum256([]byte(input))
 	return hex.EncodeToString(hash[:])
 }
 
+// SHA512Hash computes SHA512 hash of input
+func SHA512Hash(input string) string {
+	hash := sha512.Sum512([]byte(input))
+	return hex.EncodeToString(hash[:])
+}
+
+// HashAlgo identifies a hashing algorithm supported by Hash
+type HashAlgo int
+
+const (
+	// SHA256 selects the SHA-256 algorithm
+	SHA256 HashAlgo = iota
+	// SHA512 selects the SHA-512 algorithm
+	SHA512
+)
+
+// Hash computes the hex-encoded hash of input using the given algorithm
+func Hash(input string, algo HashAlgo) (string, error) {
+	switch algo {
+	case SHA256:
+		return SHA256Hash(input), nil
+	case SHA512:
+		return SHA512Hash(input), nil
+	default:
+		return "", errors.New("unsupported hash algorithm")
+	}
+}
+
+// SecureCompare compares two secrets in constant time and is safe for API keys and tokens
+// Both inputs are hashed first so that differing lengths do not leak through timing
+func SecureCompare(a, b string) bool {
+	hashA := sha256.Sum256([]byte(a))
+	hashB := sha256.Sum256([]byte(b))
+	return subtle.ConstantTimeCompare(hashA[:], hashB[:]) == 1
+}
+
+// PBKDF2Iterations is the iteration count used by DeriveKey
+// OWASP recommends at least 600,000 iterations for PBKDF2-HMAC-SHA256; raise it as hardware improves
+var PBKDF2Iterations = 600000
+
+// GenerateSalt generates n random bytes for use as a key derivation salt
+func GenerateSalt(n int) ([]byte, error) {
+	if n <= 0 {
+		return nil, errors.New("salt length must be positive")
+	}
+	salt := make([]byte, n)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return salt, nil
+}
+
+// DeriveKey derives a keyLen byte key from a passphrase using PBKDF2-HMAC-SHA256
+// Use a random salt of at least 16 bytes from GenerateSalt and store it alongside the ciphertext
+func DeriveKey(passphrase string, salt []byte, keyLen int) []byte {
+	return pbkdf2.Key([]byte(passphrase), salt, PBKDF2Iterations, keyLen, sha256.New)
+}
+
+// ExpandKey derives a keyLen byte subkey from a high-entropy master secret using HKDF-SHA256
+// Distinct info values (e.g. "encryption" and "mac") yield independent keys from the same master
+func ExpandKey(master, salt, info []byte, keyLen int) ([]byte, error) {
+	if keyLen <= 0 {
+		return nil, errors.New("key length must be positive")
+	}
+	key := make([]byte, keyLen)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, master, salt, info), key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// SignECDSA signs the SHA256 digest of data and returns an ASN.1-encoded signature
+func SignECDSA(data []byte, priv *ecdsa.PrivateKey) ([]byte, error) {
+	if priv == nil {
+		return nil, errors.New("private key is nil")
+	}
+	digest := sha256.Sum256(data)
+	return ecdsa.SignASN1(rand.Reader, priv, digest[:])
+}
+
+// VerifyECDSA verifies an ASN.1-encoded signature over the SHA256 digest of data
+func VerifyECDSA(data, sig []byte, pub *ecdsa.PublicKey) bool {
+	if pub == nil {
+		return false
+	}
+	digest := sha256.Sum256(data)
+	return ecdsa.VerifyASN1(pub, digest[:], sig)
+}
+
 // SimpleHash computes a simple hash using C implementation (for demonstration)
 // This uses CGo to call C code, demonstrating cross-language integration
 func SimpleHash(input string) int {
@@ -49,18 +199,143 @@ func SimpleHash(input string) int {
 	return int(C.simple_hash(cInput))
 }
 
+// NonCryptoHash returns the C simple_hash of input as an unsigned 32-bit value
+// It is trivially collidable and must only be used for bucketing or demonstration, never for security
+func NonCryptoHash(input string) uint32 {
+	return uint32(SimpleHash(input) & 0xffffffff)
+}
+
 // XOREncrypt performs XOR encryption using C implementation
+// This is for demonstration only and provides no real confidentiality; use EncryptAESGCM instead
 func XOREncrypt(input []byte, key byte) []byte {
 	if len(input) == 0 {
 		return nil
 	}
 	output := make([]byte, len(input))
+	xorInto(output, input, key)
+	return output
+}
+
+// XORDecrypt reverses XOREncrypt; XOR is symmetric so this is the same operation
+// Like XOREncrypt, this is for demonstration only
+func XORDecrypt(input []byte, key byte) []byte {
+	return XOREncrypt(input, key)
+}
+
+// xorChunkSize is the buffer size XORStream processes per C call
+const xorChunkSize = 32 * 1024
+
+// XORStream XORs everything read from r with key and writes it to w in fixed-size chunks
+// Like XOREncrypt, this is for demonstration only
+func XORStream(r io.Reader, w io.Writer, key byte) error {
+	buf := make([]byte, xorChunkSize)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			xorInto(buf[:n], buf[:n], key)
+			if _, werr := w.Write(buf[:n]); werr != nil {
+				return werr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// xorInto XORs src with key into dst using the C implementation; dst may alias src
+func xorInto(dst, src []byte, key byte) {
+	if len(src) == 0 {
+		return
+	}
 	C.xor_encrypt(
-		(*C.uchar)(&input[0]),
-		(*C.uchar)(&output[0]),
-		C.int(len(input)),
+		(*C.uchar)(&src[0]),
+		(*C.uchar)(&dst[0]),
+		C.int(len(src)),
 		C.uchar(key),
 	)
-	return output
 }
 
+// gcmNonceSize is the nonce length prepended to AES-GCM ciphertexts
+const gcmNonceSize = 12
+
+// EncryptAESGCM encrypts plaintext with AES-GCM using a 16, 24 or 32 byte key
+// The random nonce is prepended to the returned ciphertext
+func EncryptAESGCM(plaintext, key []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcmNonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// DecryptAESGCM decrypts and authenticates ciphertext produced by EncryptAESGCM
+func DecryptAESGCM(ciphertext, key []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < gcmNonceSize+gcm.Overhead() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, sealed := ciphertext[:gcmNonceSize], ciphertext[gcmNonceSize:]
+	return gcm.Open(nil, nonce, sealed, nil)
+}
+
+// Encryptor encrypts and decrypts opaque byte payloads
+type Encryptor interface {
+	Encrypt(plaintext []byte) ([]byte, error)
+	Decrypt(ciphertext []byte) ([]byte, error)
+}
+
+// AESGCMEncryptor is an Encryptor backed by EncryptAESGCM and DecryptAESGCM
+type AESGCMEncryptor struct {
+	key []byte
+}
+
+// NewAESGCMEncryptor creates an AESGCMEncryptor using a 16, 24 or 32 byte key
+func NewAESGCMEncryptor(key []byte) (*AESGCMEncryptor, error) {
+	if _, err := newGCM(key); err != nil {
+		return nil, err
+	}
+	return &AESGCMEncryptor{key: append([]byte(nil), key...)}, nil
+}
+
+// Encrypt encrypts plaintext with AES-GCM
+func (e *AESGCMEncryptor) Encrypt(plaintext []byte) ([]byte, error) {
+	return EncryptAESGCM(plaintext, e.key)
+}
+
+// Decrypt decrypts ciphertext produced by Encrypt
+func (e *AESGCMEncryptor) Decrypt(ciphertext []byte) ([]byte, error) {
+	return DecryptAESGCM(ciphertext, e.key)
+}
+
+// Zero overwrites b with zeros, e.g. to wipe decrypted plaintext once it is no longer needed
+// runtime.KeepAlive keeps the slice reachable past the writes so they cannot be treated as dead stores
+func Zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+	runtime.KeepAlive(b)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	switch len(key) {
+	case 16, 24, 32:
+	default:
+		return nil, errors.New("AES key must be 16, 24 or 32 bytes")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCMWithNonceSize(block, gcmNonceSize)
+}
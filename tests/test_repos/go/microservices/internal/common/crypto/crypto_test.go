@@ -1,6 +1,16 @@
 package crypto
 
-import "testing"
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+	"testing"
+)
 
 func TestHashPassword(t *testing.T) {
 	hash, err := HashPassword("testpassword")
@@ -21,3 +31,314 @@ func TestCheckPasswordHash(t *testing.T) {
 		t.Error("CheckPasswordHash succeeded for wrong password")
 	}
 }
+
+func TestCheckPasswordStrength(t *testing.T) {
+	if err := CheckPasswordStrength("Ab1"); err == nil || !strings.Contains(err.Error(), "characters") {
+		t.Errorf("expected length error for too-short password, got %v", err)
+	}
+	if err := CheckPasswordStrength("alllowercase"); err == nil || !strings.Contains(err.Error(), "uppercase") {
+		t.Errorf("expected uppercase error for all-lowercase password, got %v", err)
+	}
+	if err := CheckPasswordStrength("Str0ngPassw0rd"); err != nil {
+		t.Errorf("strong password rejected: %v", err)
+	}
+}
+
+func TestCheckPasswordPeppered(t *testing.T) {
+	hash, err := HashPasswordPeppered("testpassword", "pepper-one")
+	if err != nil {
+		t.Fatalf("HashPasswordPeppered failed: %v", err)
+	}
+	if !CheckPasswordPeppered("testpassword", "pepper-one", hash) {
+		t.Error("CheckPasswordPeppered failed for correct password and pepper")
+	}
+	if CheckPasswordPeppered("testpassword", "pepper-two", hash) {
+		t.Error("CheckPasswordPeppered succeeded with a different pepper")
+	}
+	if CheckPasswordPeppered("wrongpassword", "pepper-one", hash) {
+		t.Error("CheckPasswordPeppered succeeded for wrong password")
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("testpassword"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("GenerateFromPassword failed: %v", err)
+	}
+
+	needs, err := NeedsRehash(string(hash), bcrypt.DefaultCost)
+	if err != nil {
+		t.Fatalf("NeedsRehash failed: %v", err)
+	}
+	if !needs {
+		t.Error("NeedsRehash returned false for a hash below the desired cost")
+	}
+
+	needs, err = NeedsRehash(string(hash), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("NeedsRehash failed: %v", err)
+	}
+	if needs {
+		t.Error("NeedsRehash returned true for a hash at the desired cost")
+	}
+}
+
+func TestNeedsRehashMalformedHash(t *testing.T) {
+	if _, err := NeedsRehash("not-a-bcrypt-hash", bcrypt.DefaultCost); err == nil {
+		t.Error("NeedsRehash succeeded for a malformed hash")
+	}
+}
+
+func TestEncryptDecryptAESGCM(t *testing.T) {
+	key := bytes.Repeat([]byte{0x42}, 32)
+	plaintext := []byte("sensitive payload")
+
+	ciphertext, err := EncryptAESGCM(plaintext, key)
+	if err != nil {
+		t.Fatalf("EncryptAESGCM failed: %v", err)
+	}
+	if bytes.Contains(ciphertext, plaintext) {
+		t.Error("ciphertext contains the plaintext")
+	}
+
+	decrypted, err := DecryptAESGCM(ciphertext, key)
+	if err != nil {
+		t.Fatalf("DecryptAESGCM failed: %v", err)
+	}
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Errorf("DecryptAESGCM returned %q, expected %q", decrypted, plaintext)
+	}
+
+	ciphertext[len(ciphertext)-1] ^= 0xff
+	if _, err := DecryptAESGCM(ciphertext, key); err == nil {
+		t.Error("DecryptAESGCM succeeded for tampered ciphertext")
+	}
+}
+
+func ExampleDecryptAESGCM() {
+	key := bytes.Repeat([]byte{0x42}, 32)
+	defer Zero(key)
+
+	ciphertext, _ := EncryptAESGCM([]byte("card number"), key)
+	plaintext, err := DecryptAESGCM(ciphertext, key)
+	if err != nil {
+		return
+	}
+	defer Zero(plaintext)
+
+	fmt.Println(string(plaintext))
+	// Output: card number
+}
+
+type noopEncryptor struct{}
+
+func (noopEncryptor) Encrypt(plaintext []byte) ([]byte, error)  { return plaintext, nil }
+func (noopEncryptor) Decrypt(ciphertext []byte) ([]byte, error) { return ciphertext, nil }
+
+func TestEncryptor(t *testing.T) {
+	aesEncryptor, err := NewAESGCMEncryptor(bytes.Repeat([]byte{0x42}, 16))
+	if err != nil {
+		t.Fatalf("NewAESGCMEncryptor failed: %v", err)
+	}
+
+	for _, encryptor := range []Encryptor{aesEncryptor, noopEncryptor{}} {
+		ciphertext, err := encryptor.Encrypt([]byte("payload"))
+		if err != nil {
+			t.Fatalf("%T.Encrypt failed: %v", encryptor, err)
+		}
+		plaintext, err := encryptor.Decrypt(ciphertext)
+		if err != nil {
+			t.Fatalf("%T.Decrypt failed: %v", encryptor, err)
+		}
+		if string(plaintext) != "payload" {
+			t.Errorf("%T round trip returned %q", encryptor, plaintext)
+		}
+	}
+
+	if _, err := NewAESGCMEncryptor([]byte("short")); err == nil {
+		t.Error("NewAESGCMEncryptor succeeded with an invalid key length")
+	}
+}
+
+func TestZero(t *testing.T) {
+	b := []byte("sensitive plaintext")
+	Zero(b)
+	for i, v := range b {
+		if v != 0 {
+			t.Fatalf("byte %d is %#x after Zero", i, v)
+		}
+	}
+	Zero(nil)
+}
+
+func TestEncryptAESGCMInvalidKey(t *testing.T) {
+	if _, err := EncryptAESGCM([]byte("data"), []byte("short")); err == nil {
+		t.Error("EncryptAESGCM succeeded with an invalid key length")
+	}
+}
+
+func TestSecureCompare(t *testing.T) {
+	if !SecureCompare("api-key-123", "api-key-123") {
+		t.Error("SecureCompare returned false for equal inputs")
+	}
+	if SecureCompare("api-key-123", "api-key-124") {
+		t.Error("SecureCompare returned true for unequal inputs of the same length")
+	}
+	if SecureCompare("api-key-123", "api-key-123-extra") {
+		t.Error("SecureCompare returned true for inputs of differing lengths")
+	}
+	if SecureCompare("", "api-key-123") {
+		t.Error("SecureCompare returned true for an empty input")
+	}
+}
+
+func TestHash(t *testing.T) {
+	sha256Hash, err := Hash("input", SHA256)
+	if err != nil {
+		t.Fatalf("Hash(SHA256) failed: %v", err)
+	}
+	if sha256Hash != SHA256Hash("input") || len(sha256Hash) != 64 {
+		t.Errorf("Hash(SHA256) returned %q", sha256Hash)
+	}
+
+	sha512Hash, err := Hash("input", SHA512)
+	if err != nil {
+		t.Fatalf("Hash(SHA512) failed: %v", err)
+	}
+	if sha512Hash != SHA512Hash("input") || len(sha512Hash) != 128 {
+		t.Errorf("Hash(SHA512) returned %q", sha512Hash)
+	}
+
+	if _, err := Hash("input", HashAlgo(99)); err == nil {
+		t.Error("Hash succeeded for an unknown algorithm")
+	}
+}
+
+func TestGenerateRandomTokenURLSafe(t *testing.T) {
+	token, err := GenerateRandomTokenURLSafe(32)
+	if err != nil {
+		t.Fatalf("GenerateRandomTokenURLSafe failed: %v", err)
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		t.Fatalf("token is not valid base64url: %v", err)
+	}
+	if len(decoded) != 32 {
+		t.Errorf("decoded token has %d bytes, expected 32", len(decoded))
+	}
+
+	if _, err := GenerateRandomTokenURLSafe(0); err == nil {
+		t.Error("GenerateRandomTokenURLSafe succeeded with zero length")
+	}
+}
+
+func TestDeriveKey(t *testing.T) {
+	salt, err := GenerateSalt(16)
+	if err != nil {
+		t.Fatalf("GenerateSalt failed: %v", err)
+	}
+
+	key := DeriveKey("correct horse battery staple", salt, 32)
+	if len(key) != 32 {
+		t.Fatalf("DeriveKey returned %d bytes, expected 32", len(key))
+	}
+	if !bytes.Equal(key, DeriveKey("correct horse battery staple", salt, 32)) {
+		t.Error("DeriveKey is not deterministic for the same passphrase and salt")
+	}
+	if bytes.Equal(key, DeriveKey("another passphrase", salt, 32)) {
+		t.Error("DeriveKey returned the same key for different passphrases")
+	}
+
+	if _, err := EncryptAESGCM([]byte("data"), key); err != nil {
+		t.Errorf("derived key rejected by EncryptAESGCM: %v", err)
+	}
+}
+
+func TestXORDecrypt(t *testing.T) {
+	plaintext := []byte("hello")
+	if !bytes.Equal(XORDecrypt(XOREncrypt(plaintext, 0x5a), 0x5a), plaintext) {
+		t.Error("XORDecrypt did not reverse XOREncrypt")
+	}
+}
+
+func TestXORStream(t *testing.T) {
+	plaintext := bytes.Repeat([]byte("stream data "), xorChunkSize/4)
+
+	var encrypted bytes.Buffer
+	if err := XORStream(bytes.NewReader(plaintext), &encrypted, 0x5a); err != nil {
+		t.Fatalf("XORStream failed: %v", err)
+	}
+	if !bytes.Equal(encrypted.Bytes(), XOREncrypt(plaintext, 0x5a)) {
+		t.Error("XORStream output differs from XOREncrypt")
+	}
+
+	var decrypted bytes.Buffer
+	if err := XORStream(&encrypted, &decrypted, 0x5a); err != nil {
+		t.Fatalf("XORStream failed: %v", err)
+	}
+	if !bytes.Equal(decrypted.Bytes(), plaintext) {
+		t.Error("XORStream round trip did not restore the plaintext")
+	}
+}
+
+func TestXORStreamEmptyInput(t *testing.T) {
+	var out bytes.Buffer
+	if err := XORStream(bytes.NewReader(nil), &out, 0x5a); err != nil {
+		t.Fatalf("XORStream failed for empty input: %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("XORStream wrote %d bytes for empty input", out.Len())
+	}
+}
+
+func TestExpandKey(t *testing.T) {
+	master := bytes.Repeat([]byte{0x17}, 32)
+	salt := []byte("salt")
+
+	encryptionKey, err := ExpandKey(master, salt, []byte("encryption"), 32)
+	if err != nil {
+		t.Fatalf("ExpandKey failed: %v", err)
+	}
+	macKey, err := ExpandKey(master, salt, []byte("mac"), 32)
+	if err != nil {
+		t.Fatalf("ExpandKey failed: %v", err)
+	}
+
+	if len(encryptionKey) != 32 || len(macKey) != 32 {
+		t.Errorf("ExpandKey returned %d and %d bytes, expected 32", len(encryptionKey), len(macKey))
+	}
+	if bytes.Equal(encryptionKey, macKey) {
+		t.Error("ExpandKey returned the same key for different info values")
+	}
+}
+
+func TestSignVerifyECDSA(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	data := []byte(`{"order_id":"123","amount":100}`)
+
+	sig, err := SignECDSA(data, priv)
+	if err != nil {
+		t.Fatalf("SignECDSA failed: %v", err)
+	}
+	if !VerifyECDSA(data, sig, &priv.PublicKey) {
+		t.Error("VerifyECDSA failed for a valid signature")
+	}
+
+	tampered := []byte(`{"order_id":"123","amount":999}`)
+	if VerifyECDSA(tampered, sig, &priv.PublicKey) {
+		t.Error("VerifyECDSA succeeded for tampered data")
+	}
+}
+
+func TestNonCryptoHash(t *testing.T) {
+	first := NonCryptoHash("bucket-key")
+	if first != NonCryptoHash("bucket-key") {
+		t.Error("NonCryptoHash is not stable across calls")
+	}
+	if first != uint32(SimpleHash("bucket-key")) {
+		t.Errorf("NonCryptoHash returned %d, expected %d", first, SimpleHash("bucket-key"))
+	}
+}
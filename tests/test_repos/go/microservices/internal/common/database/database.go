@@ -1,49 +1,421 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"github.com/go-sql-driver/mysql"
 	"github.com/greenfuze/go-microservices/internal/common/config"
 	"github.com/greenfuze/go-microservices/internal/common/logger"
+	"github.com/greenfuze/go-microservices/internal/common/metrics"
 	_ "github.com/lib/pq"
+	"go.uber.org/zap"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
-var db *sql.DB
+// Supported values for config.DatabaseConfig.Driver
+const (
+	DriverPostgres = "postgres"
+	DriverMySQL    = "mysql"
+)
+
+const (
+	// defaultPort is used when the configured postgres port is zero
+	defaultPort = 5432
+	// defaultMySQLPort is used when the configured mysql port is zero
+	defaultMySQLPort = 3306
+	// defaultSSLMode is used when no sslmode is configured
+	defaultSSLMode = "disable"
+)
+
+// ErrNotConnected is returned when the database is used before a successful Connect
+var ErrNotConnected = errors.New("database not connected")
+
+// DefaultQueryTimeout bounds QueryContext and ExecContext calls whose context has no deadline
+// A zero value disables the default timeout
+var DefaultQueryTimeout = 5 * time.Second
+
+// ReconnectAfterFailures makes HealthCheck call Reconnect after this many consecutive failures
+// A zero value disables automatic reconnects
+var ReconnectAfterFailures = 0
+
+// openDB opens a pool and is replaceable in tests
+var openDB = sql.Open
+
+var (
+	// db holds the current pool; it is swapped atomically by Reconnect while requests read it
+	db                  atomic.Pointer[sql.DB]
+	lastConfig          *config.DatabaseConfig
+	lastDSN             DSN // set by ConnectReplicas, overriding the DSN built from lastConfig
+	reconnectMu         sync.Mutex
+	healthCheckFailures atomic.Int32
+)
 
 // Connect establishes a database connection
 func Connect() (*sql.DB, error) {
+	return ConnectContext(context.Background())
+}
+
+// ConnectContext establishes a database connection, bounding the initial ping by ctx
+func ConnectContext(ctx context.Context) (*sql.DB, error) {
 	cfg := config.GetConfig()
 	if cfg == nil {
-		return nil, logger.GetLogger().Sugar().Error("Config not loaded")
+		return nil, errors.New("config not loaded")
 	}
+	return connect(ctx, cfg.Database.Pool)
+}
 
-	dsn := "postgres://" + cfg.Database.User + ":" + cfg.Database.Password +
-		"@" + cfg.Database.Host + ":5432" +
-		"/" + cfg.Database.DBName + "?sslmode=disable"
+// ConnectWithPool establishes a database connection using the given pool settings
+// instead of the configured ones
+func ConnectWithPool(pool config.PoolConfig) (*sql.DB, error) {
+	return connect(context.Background(), pool)
+}
+
+func connect(ctx context.Context, pool config.PoolConfig) (*sql.DB, error) {
+	cfg := config.GetConfig()
+	if cfg == nil {
+		return nil, errors.New("config not loaded")
+	}
+
+	dbConfig := cfg.Database
+	dbConfig.Pool = pool
+	conn, err := openPool(ctx, dbConfig)
 
-	var err error
-	db, err = sql.Open("postgres", dsn)
 	if err != nil {
+		// A working pool from an earlier connect stays in place
 		return nil, err
 	}
 
-	if err = db.Ping(); err != nil {
+	reconnectMu.Lock()
+	previous := db.Swap(conn)
+	lastConfig = &dbConfig
+	lastDSN = ""
+	reconnectMu.Unlock()
+	if previous != nil {
+		previous.Close()
+	}
+	logger.Info("Database connection established")
+	return conn, nil
+}
+
+// openPool opens and pings a new pool for the given configuration
+func openPool(ctx context.Context, dbConfig config.DatabaseConfig) (*sql.DB, error) {
+	driver, err := driverName(dbConfig)
+	if err != nil {
 		return nil, err
 	}
+	return openDSN(ctx, driver, DSN(buildDSN(dbConfig)), dbConfig.Pool)
+}
 
-	logger.Info("Database connection established")
-	return db, nil
+// openDSN opens a pool for dsn with the given pool settings and pings it
+func openDSN(ctx context.Context, driver string, dsn DSN, pool config.PoolConfig) (*sql.DB, error) {
+	conn, err := openDB(driver, string(dsn))
+	if err != nil {
+		return nil, err
+	}
+	applyPool(conn, pool)
+
+	if err = conn.PingContext(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// Reconnect closes the current pool and rebuilds it from the last configuration passed to Connect,
+// or from the primary DSN passed to ConnectReplicas
+// In-flight queries and transactions on the old pool are not preserved and will fail
+func Reconnect() error {
+	reconnectMu.Lock()
+	defer reconnectMu.Unlock()
+
+	if lastConfig == nil {
+		return errors.New("database was never connected")
+	}
+
+	driver, err := driverName(*lastConfig)
+	if err != nil {
+		return err
+	}
+	dsn := lastDSN
+	if dsn == "" {
+		dsn = DSN(buildDSN(*lastConfig))
+	}
+
+	conn, err := openDSN(context.Background(), driver, dsn, lastConfig.Pool)
+	if err != nil {
+		return err
+	}
+
+	if previous := db.Swap(conn); previous != nil {
+		previous.Close()
+	}
+	logger.Info("Database connection re-established")
+	return nil
+}
+
+// ConnectWithRetry calls ConnectContext up to attempts times, waiting with exponential
+// backoff and jitter between failures, and returns the last error on exhaustion
+func ConnectWithRetry(ctx context.Context, attempts int, baseDelay time.Duration) (*sql.DB, error) {
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		var conn *sql.DB
+		if conn, err = ConnectContext(ctx); err == nil {
+			return conn, nil
+		}
+		if attempt == attempts {
+			break
+		}
+
+		delay := backoffDelay(baseDelay, attempt)
+		logger.Info("Database connection failed, retrying",
+			zap.Int("attempt", attempt), zap.Duration("delay", delay), zap.Error(err))
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return nil, err
 }
 
-// GetDB returns the database connection
-func GetDB() *sql.DB {
-	return db
+// backoffDelay returns baseDelay doubled for each failed attempt plus up to 50% random jitter
+func backoffDelay(baseDelay time.Duration, attempt int) time.Duration {
+	delay := baseDelay << (attempt - 1)
+	if delay <= 0 {
+		return baseDelay
+	}
+	return delay + time.Duration(rand.Int63n(int64(delay)/2+1))
+}
+
+// driverName returns the database/sql driver name for the configuration, defaulting to postgres
+func driverName(cfg config.DatabaseConfig) (string, error) {
+	switch cfg.Driver {
+	case "", DriverPostgres:
+		return DriverPostgres, nil
+	case DriverMySQL:
+		return DriverMySQL, nil
+	default:
+		return "", errors.New("unsupported database driver: " + cfg.Driver)
+	}
+}
+
+// buildDSN builds a connection string in the format expected by the configured driver
+func buildDSN(cfg config.DatabaseConfig) string {
+	if cfg.Driver == DriverMySQL {
+		return buildMySQLDSN(cfg)
+	}
+	return buildPostgresDSN(cfg)
+}
+
+// buildPostgresDSN builds a postgres connection string from the database configuration
+func buildPostgresDSN(cfg config.DatabaseConfig) string {
+	port := cfg.Port
+	if port == 0 {
+		port = defaultPort
+	}
+	sslMode := cfg.SSLMode
+	if sslMode == "" {
+		sslMode = defaultSSLMode
+	}
+	return "postgres://" + cfg.User + ":" + cfg.Password +
+		"@" + cfg.Host + ":" + strconv.Itoa(port) +
+		"/" + cfg.DBName + "?sslmode=" + sslMode
+}
+
+// buildMySQLDSN builds a go-sql-driver/mysql connection string from the database configuration
+// SSLMode is mapped onto the driver's tls parameter: require skips verification, verify-full verifies
+// multiStatements is enabled for RunMigrations, so never build queries by concatenating input
+func buildMySQLDSN(cfg config.DatabaseConfig) string {
+	port := cfg.Port
+	if port == 0 {
+		port = defaultMySQLPort
+	}
+
+	mysqlConfig := mysql.NewConfig()
+	mysqlConfig.User = cfg.User
+	mysqlConfig.Passwd = cfg.Password
+	mysqlConfig.Net = "tcp"
+	mysqlConfig.Addr = net.JoinHostPort(cfg.Host, strconv.Itoa(port))
+	mysqlConfig.DBName = cfg.DBName
+	mysqlConfig.ParseTime = true
+	// Migration files hold several statements that RunMigrations executes in one call
+	mysqlConfig.MultiStatements = true
+	switch cfg.SSLMode {
+	case "require":
+		mysqlConfig.TLSConfig = "skip-verify"
+	case "verify-ca", "verify-full":
+		mysqlConfig.TLSConfig = "true"
+	}
+	return mysqlConfig.FormatDSN()
+}
+
+// applyPool applies the non-zero pool settings to db, leaving database/sql defaults for the rest
+func applyPool(db *sql.DB, pool config.PoolConfig) {
+	if pool.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(pool.MaxOpenConns)
+	}
+	if pool.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(pool.MaxIdleConns)
+	}
+	if pool.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(pool.ConnMaxLifetime)
+	}
+	if pool.ConnMaxIdleTime > 0 {
+		db.SetConnMaxIdleTime(pool.ConnMaxIdleTime)
+	}
+}
+
+// GetDB returns the database connection, or ErrNotConnected if Connect has not succeeded
+func GetDB() (*sql.DB, error) {
+	conn := db.Load()
+	if conn == nil {
+		return nil, ErrNotConnected
+	}
+	return conn, nil
+}
+
+// MustGetDB returns the database connection and panics if Connect has not succeeded
+func MustGetDB() *sql.DB {
+	conn, err := GetDB()
+	if err != nil {
+		panic("database: MustGetDB called before a successful Connect")
+	}
+	return conn
+}
+
+// WithTransaction runs fn inside a transaction, committing when fn returns nil and
+// rolling back when it returns an error or panics
+func WithTransaction(ctx context.Context, fn func(*sql.Tx) error) (err error) {
+	conn := db.Load()
+	if conn == nil {
+		return ErrNotConnected
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err = fn(tx); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			logger.Error("Transaction rollback failed", zap.Error(rbErr))
+		}
+		return err
+	}
+	return tx.Commit()
+}
+
+// Rows wraps *sql.Rows so that closing it also releases the query timeout
+type Rows struct {
+	*sql.Rows
+	cancel context.CancelFunc
+}
+
+// Close closes the rows and releases the query timeout
+func (r *Rows) Close() error {
+	err := r.Rows.Close()
+	r.cancel()
+	return err
+}
+
+// QueryContext runs a query on the current connection with the default timeout applied
+// Its duration is recorded via metrics.RecordDBQuery under the statement's leading keyword
+func QueryContext(ctx context.Context, query string, args ...interface{}) (*Rows, error) {
+	conn := db.Load()
+	if conn == nil {
+		return nil, ErrNotConnected
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	start := time.Now()
+	rows, err := conn.QueryContext(ctx, query, args...)
+	metrics.RecordDBQuery(queryOperation(query), time.Since(start).Seconds())
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return &Rows{Rows: rows, cancel: cancel}, nil
+}
+
+// ExecContext executes a statement on the current connection with the default timeout applied
+// Its duration is recorded via metrics.RecordDBQuery under the statement's leading keyword
+func ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	conn := db.Load()
+	if conn == nil {
+		return nil, ErrNotConnected
+	}
+
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+	start := time.Now()
+	result, err := conn.ExecContext(ctx, query, args...)
+	metrics.RecordDBQuery(queryOperation(query), time.Since(start).Seconds())
+	return result, err
+}
+
+// queryOperation returns the lowercased leading keyword of query, e.g. "select" or "insert"
+func queryOperation(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return "unknown"
+	}
+	return strings.ToLower(fields[0])
+}
+
+// withQueryTimeout applies DefaultQueryTimeout unless ctx already carries a deadline
+func withQueryTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok || DefaultQueryTimeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, DefaultQueryTimeout)
+}
+
+// HealthCheck pings the current database connection, for use by readiness probes
+// When ReconnectAfterFailures is set, repeated failures trigger a Reconnect
+func HealthCheck(ctx context.Context) error {
+	conn := db.Load()
+	if conn == nil {
+		return ErrNotConnected
+	}
+
+	err := conn.PingContext(ctx)
+	if err == nil {
+		healthCheckFailures.Store(0)
+		return nil
+	}
+
+	if ReconnectAfterFailures > 0 && int(healthCheckFailures.Add(1)) >= ReconnectAfterFailures {
+		healthCheckFailures.Store(0)
+		if rcErr := Reconnect(); rcErr != nil {
+			logger.Error("Database reconnect failed", zap.Error(rcErr))
+		}
+	}
+	return err
 }
 
-// Close closes the database connection
+// Close closes the database connection and any read replicas
 func Close() error {
-	if db != nil {
-		return db.Close()
+	setReplicas(nil)
+	if conn := db.Load(); conn != nil {
+		return conn.Close()
 	}
 	return nil
 }
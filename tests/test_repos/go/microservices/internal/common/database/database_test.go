@@ -1,10 +1,442 @@
 package database
 
-import "testing"
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/greenfuze/go-microservices/internal/common/config"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
 
 func TestGetDB(t *testing.T) {
-	db := GetDB()
-	// In test environment, db might be nil if Connect wasn't called
-	// This is expected behavior
-	_ = db
+	// In test environment, db is nil because Connect wasn't called
+	if _, err := GetDB(); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("GetDB returned %v, expected ErrNotConnected", err)
+	}
+}
+
+func TestMustGetDBPanicsWhenNotConnected(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("MustGetDB did not panic without a connection")
+		}
+	}()
+	MustGetDB()
+}
+
+func TestBuildDSNPort(t *testing.T) {
+	cfg := config.DatabaseConfig{Host: "db", User: "app", Password: "pw", DBName: "orders", Port: 6543}
+	if dsn := buildDSN(cfg); !strings.Contains(dsn, "@db:6543/") {
+		t.Errorf("buildDSN ignored the configured port: %s", dsn)
+	}
+
+	cfg.Port = 0
+	if dsn := buildDSN(cfg); !strings.Contains(dsn, "@db:5432/") {
+		t.Errorf("buildDSN did not default the port to 5432: %s", dsn)
+	}
+}
+
+func TestBuildDSNSSLMode(t *testing.T) {
+	cfg := config.DatabaseConfig{Host: "db", User: "app", Password: "pw", DBName: "orders"}
+	if dsn := buildDSN(cfg); !strings.HasSuffix(dsn, "?sslmode=disable") {
+		t.Errorf("buildDSN did not default sslmode to disable: %s", dsn)
+	}
+
+	for _, mode := range []string{"disable", "require", "verify-full"} {
+		cfg.SSLMode = mode
+		if dsn := buildDSN(cfg); !strings.HasSuffix(dsn, "?sslmode="+mode) {
+			t.Errorf("buildDSN ignored sslmode %s: %s", mode, dsn)
+		}
+	}
+}
+
+func TestBuildDSNDrivers(t *testing.T) {
+	cfg := config.DatabaseConfig{Host: "db", User: "app", Password: "pw", DBName: "orders"}
+
+	cfg.Driver = DriverPostgres
+	if dsn := buildDSN(cfg); dsn != "postgres://app:pw@db:5432/orders?sslmode=disable" {
+		t.Errorf("unexpected postgres DSN: %s", dsn)
+	}
+
+	cfg.Driver = DriverMySQL
+	if dsn := buildDSN(cfg); dsn != "app:pw@tcp(db:3306)/orders?multiStatements=true&parseTime=true" {
+		t.Errorf("unexpected mysql DSN: %s", dsn)
+	}
+
+	cfg.SSLMode = "verify-full"
+	if dsn := buildDSN(cfg); !strings.Contains(dsn, "tls=true") {
+		t.Errorf("mysql DSN does not enable verified TLS: %s", dsn)
+	}
+}
+
+func TestDriverName(t *testing.T) {
+	if driver, err := driverName(config.DatabaseConfig{}); err != nil || driver != DriverPostgres {
+		t.Errorf("driverName defaulted to %q (%v), expected postgres", driver, err)
+	}
+	if driver, err := driverName(config.DatabaseConfig{Driver: DriverMySQL}); err != nil || driver != DriverMySQL {
+		t.Errorf("driverName returned %q (%v), expected mysql", driver, err)
+	}
+	if _, err := driverName(config.DatabaseConfig{Driver: "oracle"}); err == nil {
+		t.Error("driverName accepted an unsupported driver")
+	}
+}
+
+func TestApplyPool(t *testing.T) {
+	pool, err := sql.Open("postgres", buildDSN(config.DatabaseConfig{Host: "localhost"}))
+	if err != nil {
+		t.Fatalf("sql.Open failed: %v", err)
+	}
+	defer pool.Close()
+
+	applyPool(pool, config.PoolConfig{MaxOpenConns: 7, MaxIdleConns: 3})
+	if max := pool.Stats().MaxOpenConnections; max != 7 {
+		t.Errorf("MaxOpenConnections = %d, expected 7", max)
+	}
+}
+
+func TestConnectContextHonorsDeadline(t *testing.T) {
+	if _, err := config.LoadConfig(); err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	config.GetConfig().Database.Host = "10.255.255.1"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := ConnectContext(ctx); err == nil {
+		t.Fatal("ConnectContext succeeded against an unreachable host")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("ConnectContext took %v despite a 100ms deadline", elapsed)
+	}
+}
+
+func TestBackoffDelay(t *testing.T) {
+	base := 100 * time.Millisecond
+	for attempt := 1; attempt <= 4; attempt++ {
+		min := base << (attempt - 1)
+		max := min + min/2
+		if delay := backoffDelay(base, attempt); delay < min || delay > max {
+			t.Errorf("backoffDelay(%v, %d) = %v, expected between %v and %v", base, attempt, delay, min, max)
+		}
+	}
+}
+
+func TestConnectWithRetryReturnsLastError(t *testing.T) {
+	if _, err := config.LoadConfig(); err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	config.GetConfig().Database.Host = "127.0.0.1"
+	config.GetConfig().Database.Port = 1
+
+	if _, err := ConnectWithRetry(context.Background(), 3, time.Millisecond); err == nil {
+		t.Fatal("ConnectWithRetry succeeded against a closed port")
+	}
+	if _, err := GetDB(); err == nil {
+		t.Error("GetDB returned a pool after all connection attempts failed")
+	}
+}
+
+func TestHealthCheckNotConnected(t *testing.T) {
+	if err := HealthCheck(context.Background()); !errors.Is(err, ErrNotConnected) {
+		t.Error("HealthCheck succeeded without a database connection")
+	}
+}
+
+func TestWithTransactionRollsBackOnError(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New failed: %v", err)
+	}
+	defer mockDB.Close()
+	db.Store(mockDB)
+	defer db.Store(nil)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO orders").WillReturnError(errors.New("insert failed"))
+	mock.ExpectRollback()
+
+	fnErr := errors.New("insert failed")
+	err = WithTransaction(context.Background(), func(tx *sql.Tx) error {
+		if _, err := tx.Exec("INSERT INTO orders (id) VALUES (1)"); err != nil {
+			return fnErr
+		}
+		return nil
+	})
+	if err != fnErr {
+		t.Errorf("WithTransaction returned %v, expected the original error", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWithTransactionCommitsOnSuccess(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New failed: %v", err)
+	}
+	defer mockDB.Close()
+	db.Store(mockDB)
+	defer db.Store(nil)
+
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	if err := WithTransaction(context.Background(), func(tx *sql.Tx) error { return nil }); err != nil {
+		t.Errorf("WithTransaction failed: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWithTransactionRollsBackOnPanic(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New failed: %v", err)
+	}
+	defer mockDB.Close()
+	db.Store(mockDB)
+	defer db.Store(nil)
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("WithTransaction swallowed the panic")
+			}
+		}()
+		WithTransaction(context.Background(), func(tx *sql.Tx) error { panic("boom") })
+	}()
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestQueryContext(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New failed: %v", err)
+	}
+	defer mockDB.Close()
+	db.Store(mockDB)
+	defer db.Store(nil)
+
+	mock.ExpectQuery("SELECT id FROM orders").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+
+	rows, err := QueryContext(context.Background(), "SELECT id FROM orders")
+	if err != nil {
+		t.Fatalf("QueryContext failed: %v", err)
+	}
+	count := 0
+	for rows.Next() {
+		count++
+	}
+	if err := rows.Close(); err != nil {
+		t.Errorf("rows.Close failed: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("QueryContext returned %d rows, expected 2", count)
+	}
+}
+
+func TestExecContextDefaultTimeout(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New failed: %v", err)
+	}
+	defer mockDB.Close()
+	db.Store(mockDB)
+	defer db.Store(nil)
+
+	previous := DefaultQueryTimeout
+	DefaultQueryTimeout = 10 * time.Millisecond
+	defer func() { DefaultQueryTimeout = previous }()
+
+	mock.ExpectExec("UPDATE orders").WillDelayFor(time.Second).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if _, err := ExecContext(context.Background(), "UPDATE orders SET status = 'paid'"); err == nil {
+		t.Error("ExecContext succeeded despite exceeding the default timeout")
+	}
+}
+
+func TestConnectReplacesPreviousPool(t *testing.T) {
+	if _, err := config.LoadConfig(); err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	newDB, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New failed: %v", err)
+	}
+	openErr := errors.New("connection refused")
+	previousOpen := openDB
+	openDB = func(driver, dsn string) (*sql.DB, error) {
+		if openErr != nil {
+			return nil, openErr
+		}
+		return newDB, nil
+	}
+	defer func() { openDB = previousOpen }()
+
+	oldDB, oldMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New failed: %v", err)
+	}
+	db.Store(oldDB)
+	defer func() {
+		Close()
+		db.Store(nil)
+		lastConfig = nil
+	}()
+
+	if _, err := Connect(); err == nil {
+		t.Fatal("Connect succeeded although the pool could not be opened")
+	}
+	if MustGetDB() != oldDB {
+		t.Fatal("failed Connect dropped the working pool")
+	}
+
+	openErr = nil
+	oldMock.ExpectClose()
+	if _, err := Connect(); err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	if MustGetDB() != newDB {
+		t.Error("Connect did not swap in the new pool")
+	}
+	if err := oldMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("previous pool was not closed: %v", err)
+	}
+}
+
+func TestReconnectSwapsPool(t *testing.T) {
+	mockDB, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	if err != nil {
+		t.Fatalf("sqlmock.New failed: %v", err)
+	}
+	previousOpen := openDB
+	openDB = func(driver, dsn string) (*sql.DB, error) { return mockDB, nil }
+	defer func() { openDB = previousOpen }()
+
+	oldDB, oldMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New failed: %v", err)
+	}
+	db.Store(oldDB)
+	lastConfig = &config.DatabaseConfig{Host: "db"}
+	defer func() { db.Store(nil); lastConfig = nil }()
+
+	mock.ExpectPing()
+	oldMock.ExpectClose()
+
+	if err := Reconnect(); err != nil {
+		t.Fatalf("Reconnect failed: %v", err)
+	}
+	if MustGetDB() != mockDB {
+		t.Error("Reconnect did not swap in the new pool")
+	}
+	if err := oldMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("old pool was not closed: %v", err)
+	}
+}
+
+func TestQueryContextDuringReconnect(t *testing.T) {
+	previousOpen := openDB
+	openDB = func(driver, dsn string) (*sql.DB, error) {
+		conn, _, err := sqlmock.New()
+		return conn, err
+	}
+	defer func() { openDB = previousOpen }()
+
+	initialDB, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New failed: %v", err)
+	}
+	db.Store(initialDB)
+	lastConfig = &config.DatabaseConfig{Host: "db"}
+	defer func() {
+		Close()
+		db.Store(nil)
+		lastConfig = nil
+	}()
+
+	// Queries hit pools with no expectations, or pools Reconnect has closed, so they fail;
+	// run with -race, the test checks that readers and Reconnect do not race on the pool
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if rows, err := QueryContext(context.Background(), "SELECT 1"); err == nil {
+					rows.Close()
+				}
+				HealthCheck(context.Background())
+			}
+		}()
+	}
+	for i := 0; i < 20; i++ {
+		if err := Reconnect(); err != nil {
+			t.Errorf("Reconnect failed: %v", err)
+		}
+	}
+	wg.Wait()
+}
+
+func TestHealthCheckAutoReconnect(t *testing.T) {
+	failingDB, failingMock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	if err != nil {
+		t.Fatalf("sqlmock.New failed: %v", err)
+	}
+	healthyDB, healthyMock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	if err != nil {
+		t.Fatalf("sqlmock.New failed: %v", err)
+	}
+	defer healthyDB.Close()
+
+	previousOpen := openDB
+	openDB = func(driver, dsn string) (*sql.DB, error) { return healthyDB, nil }
+	defer func() { openDB = previousOpen }()
+	previousFailures := ReconnectAfterFailures
+	ReconnectAfterFailures = 2
+	defer func() { ReconnectAfterFailures = previousFailures }()
+
+	db.Store(failingDB)
+	lastConfig = &config.DatabaseConfig{Host: "db"}
+	defer func() { db.Store(nil); lastConfig = nil }()
+
+	failingMock.ExpectPing().WillReturnError(errors.New("connection reset"))
+	failingMock.ExpectPing().WillReturnError(errors.New("connection reset"))
+	healthyMock.ExpectPing()
+
+	HealthCheck(context.Background())
+	if MustGetDB() != failingDB {
+		t.Fatal("HealthCheck reconnected before reaching the failure threshold")
+	}
+	HealthCheck(context.Background())
+	if MustGetDB() != healthyDB {
+		t.Error("HealthCheck did not reconnect after reaching the failure threshold")
+	}
+}
+
+func TestQueryOperation(t *testing.T) {
+	tests := map[string]string{
+		"SELECT id FROM orders":             "select",
+		"  insert into orders VALUES ($1)":  "insert",
+		"\n\tUPDATE orders SET status = $1": "update",
+		"":                                  "unknown",
+	}
+	for query, expected := range tests {
+		if got := queryOperation(query); got != expected {
+			t.Errorf("queryOperation(%q) = %q, expected %q", query, got, expected)
+		}
+	}
 }
@@ -0,0 +1,101 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/greenfuze/go-microservices/internal/common/logger"
+	"go.uber.org/zap"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const createMigrationsTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version VARCHAR(255) PRIMARY KEY,
+	applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`
+
+// RunMigrations applies the .sql files in dir in lexical order, one transaction per file
+// Each file's name without the extension is recorded as its version in schema_migrations,
+// and files whose version is already recorded are skipped
+func RunMigrations(ctx context.Context, dir string) error {
+	conn := db.Load()
+	if conn == nil {
+		return ErrNotConnected
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	if _, err := conn.ExecContext(ctx, createMigrationsTable); err != nil {
+		return err
+	}
+
+	applied, err := appliedMigrations(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	recordVersion := "INSERT INTO schema_migrations (version) VALUES (" + placeholder(1) + ")"
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+		version := strings.TrimSuffix(entry.Name(), ".sql")
+		if applied[version] {
+			continue
+		}
+
+		script, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return err
+		}
+
+		err = WithTransaction(ctx, func(tx *sql.Tx) error {
+			if _, err := tx.ExecContext(ctx, string(script)); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(ctx, recordVersion, version)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("migration %s failed: %w", version, err)
+		}
+		logger.Info("Applied migration", zap.String("version", version))
+	}
+	return nil
+}
+
+// appliedMigrations returns the set of versions recorded in schema_migrations
+func appliedMigrations(ctx context.Context, conn *sql.DB) (map[string]bool, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[string]bool{}
+	for rows.Next() {
+		var version string
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		applied[version] = true
+	}
+	return applied, rows.Err()
+}
+
+// placeholder returns the n-th bind parameter in the syntax of the connected driver
+func placeholder(n int) string {
+	reconnectMu.Lock()
+	isMySQL := lastConfig != nil && lastConfig.Driver == DriverMySQL
+	reconnectMu.Unlock()
+	if isMySQL {
+		return "?"
+	}
+	return "$" + strconv.Itoa(n)
+}
@@ -0,0 +1,49 @@
+package database
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func TestRunMigrations(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"001_create_users.sql": "CREATE TABLE users (id INT PRIMARY KEY)",
+		"002_add_email.sql":    "ALTER TABLE users ADD COLUMN email TEXT",
+		"README.md":            "not a migration",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+	}
+
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New failed: %v", err)
+	}
+	defer mockDB.Close()
+	db.Store(mockDB)
+	defer db.Store(nil)
+
+	mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE IF NOT EXISTS schema_migrations")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT version FROM schema_migrations")).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow("001_create_users"))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(files["002_add_email.sql"])).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO schema_migrations (version) VALUES ($1)")).
+		WithArgs("002_add_email").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if err := RunMigrations(context.Background(), dir); err != nil {
+		t.Fatalf("RunMigrations failed: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,64 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+)
+
+// PreparedCache lazily prepares statements and caches them by query string
+type PreparedCache struct {
+	db    *sql.DB
+	mu    sync.RWMutex
+	stmts map[string]*sql.Stmt
+}
+
+// NewPreparedCache creates a PreparedCache that prepares statements on conn
+func NewPreparedCache(conn *sql.DB) *PreparedCache {
+	return &PreparedCache{
+		db:    conn,
+		stmts: make(map[string]*sql.Stmt),
+	}
+}
+
+// Get returns the cached statement for query, preparing it on first use
+func (c *PreparedCache) Get(ctx context.Context, query string) (*sql.Stmt, error) {
+	c.mu.RLock()
+	stmt, ok := c.stmts[query]
+	c.mu.RUnlock()
+	if ok {
+		return stmt, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stmts == nil {
+		return nil, errors.New("prepared cache is closed")
+	}
+	if stmt, ok := c.stmts[query]; ok {
+		return stmt, nil
+	}
+
+	stmt, err := c.db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	c.stmts[query] = stmt
+	return stmt, nil
+}
+
+// Close closes every cached statement; the cache cannot be used afterwards
+func (c *PreparedCache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var errs []error
+	for _, stmt := range c.stmts {
+		if err := stmt.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	c.stmts = nil
+	return errors.Join(errs...)
+}
@@ -0,0 +1,53 @@
+package database
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"sync"
+	"testing"
+)
+
+func TestPreparedCacheConcurrentGet(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New failed: %v", err)
+	}
+	defer mockDB.Close()
+
+	mock.ExpectPrepare("SELECT id FROM orders WHERE user_id").WillBeClosed()
+
+	cache := NewPreparedCache(mockDB)
+	stmts := make(chan interface{}, 50)
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stmt, err := cache.Get(context.Background(), "SELECT id FROM orders WHERE user_id = $1")
+			if err != nil {
+				t.Errorf("Get failed: %v", err)
+				return
+			}
+			stmts <- stmt
+		}()
+	}
+	wg.Wait()
+	close(stmts)
+
+	first := <-stmts
+	for stmt := range stmts {
+		if stmt != first {
+			t.Fatal("Get returned different statements for the same query")
+		}
+	}
+
+	if err := cache.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+	if _, err := cache.Get(context.Background(), "SELECT 1"); err == nil {
+		t.Error("Get succeeded after Close")
+	}
+}
@@ -0,0 +1,135 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/greenfuze/go-microservices/internal/common/config"
+	"github.com/greenfuze/go-microservices/internal/common/logger"
+	"go.uber.org/zap"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DSN is a driver-specific data source name
+type DSN string
+
+// replica is a read-replica pool and its last known health
+type replica struct {
+	db      *sql.DB
+	healthy atomic.Bool
+}
+
+var (
+	replicas    []*replica
+	replicasMu  sync.RWMutex
+	replicaNext atomic.Uint64
+)
+
+// ReplicaPingTimeout bounds each initial ping in ConnectReplicas, so an unreachable DSN
+// cannot block startup
+var ReplicaPingTimeout = 5 * time.Second
+
+// ConnectReplicas connects to a primary and its read replicas, each with its own pool using the
+// configured pool settings
+// The primary replaces the connection returned by GetDB and is what Reconnect rebuilds;
+// unreachable replicas are kept but marked unhealthy until CheckReplicas sees them respond
+func ConnectReplicas(primary DSN, replicaDSNs []DSN) error {
+	dbConfig := config.DatabaseConfig{}
+	if cfg := config.GetConfig(); cfg != nil {
+		dbConfig = cfg.Database
+	}
+	driver, err := driverName(dbConfig)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ReplicaPingTimeout)
+	primaryDB, err := openDSN(ctx, driver, primary, dbConfig.Pool)
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	pools := make([]*replica, 0, len(replicaDSNs))
+	for i, dsn := range replicaDSNs {
+		replicaDB, err := openDB(driver, string(dsn))
+		if err != nil {
+			primaryDB.Close()
+			closeReplicas(pools)
+			return err
+		}
+		applyPool(replicaDB, dbConfig.Pool)
+
+		r := &replica{db: replicaDB}
+		ctx, cancel := context.WithTimeout(context.Background(), ReplicaPingTimeout)
+		if err := replicaDB.PingContext(ctx); err != nil {
+			logger.Error("Read replica unreachable", zap.Int("replica", i), zap.Error(err))
+		} else {
+			r.healthy.Store(true)
+		}
+		cancel()
+		pools = append(pools, r)
+	}
+
+	reconnectMu.Lock()
+	previous := db.Swap(primaryDB)
+	lastConfig = &dbConfig
+	lastDSN = primary
+	reconnectMu.Unlock()
+	if previous != nil {
+		previous.Close()
+	}
+
+	setReplicas(pools)
+	logger.Info("Database connection established", zap.Int("replicas", len(pools)))
+	return nil
+}
+
+// GetReplicaDB returns the next healthy read replica in round-robin order,
+// falling back to the primary when no replica is healthy
+func GetReplicaDB() *sql.DB {
+	replicasMu.RLock()
+	defer replicasMu.RUnlock()
+
+	n := uint64(len(replicas))
+	for i := uint64(0); i < n; i++ {
+		r := replicas[(replicaNext.Add(1)-1)%n]
+		if r.healthy.Load() {
+			return r.db
+		}
+	}
+	return db.Load()
+}
+
+// CheckReplicas pings every read replica and updates its health for GetReplicaDB
+func CheckReplicas(ctx context.Context) error {
+	replicasMu.RLock()
+	defer replicasMu.RUnlock()
+
+	var errs []error
+	for _, r := range replicas {
+		err := r.db.PingContext(ctx)
+		r.healthy.Store(err == nil)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// setReplicas replaces the replica pools, closing the previous ones
+func setReplicas(pools []*replica) {
+	replicasMu.Lock()
+	previous := replicas
+	replicas = pools
+	replicasMu.Unlock()
+	closeReplicas(previous)
+}
+
+func closeReplicas(pools []*replica) {
+	for _, r := range pools {
+		r.db.Close()
+	}
+}
@@ -0,0 +1,105 @@
+package database
+
+import (
+	"database/sql"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/greenfuze/go-microservices/internal/common/config"
+	"testing"
+)
+
+func newReplica(t *testing.T, healthy bool) *replica {
+	replicaDB, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New failed: %v", err)
+	}
+	r := &replica{db: replicaDB}
+	r.healthy.Store(healthy)
+	return r
+}
+
+func TestGetReplicaDBRoundRobin(t *testing.T) {
+	pools := []*replica{newReplica(t, true), newReplica(t, false), newReplica(t, true)}
+	setReplicas(pools)
+	defer setReplicas(nil)
+
+	seen := map[*sql.DB]int{}
+	for i := 0; i < 6; i++ {
+		seen[GetReplicaDB()]++
+	}
+	if seen[pools[0].db] != 3 || seen[pools[2].db] != 3 {
+		t.Errorf("replicas not selected evenly: %v", seen)
+	}
+	if seen[pools[1].db] != 0 {
+		t.Error("GetReplicaDB returned an unhealthy replica")
+	}
+}
+
+func TestGetReplicaDBFallsBackToPrimary(t *testing.T) {
+	primaryDB, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New failed: %v", err)
+	}
+	defer primaryDB.Close()
+	db.Store(primaryDB)
+	defer db.Store(nil)
+
+	setReplicas([]*replica{newReplica(t, false), newReplica(t, false)})
+	defer setReplicas(nil)
+
+	if GetReplicaDB() != primaryDB {
+		t.Error("GetReplicaDB did not fall back to the primary")
+	}
+}
+
+func TestConnectReplicasThenReconnectKeepsPrimary(t *testing.T) {
+	if _, err := config.LoadConfig(); err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	config.GetConfig().Database.Pool = config.PoolConfig{MaxOpenConns: 7}
+	defer func() { config.GetConfig().Database.Pool = config.PoolConfig{} }()
+
+	var opened []string
+	previousOpen := openDB
+	openDB = func(driver, dsn string) (*sql.DB, error) {
+		opened = append(opened, dsn)
+		conn, _, err := sqlmock.New()
+		return conn, err
+	}
+	defer func() { openDB = previousOpen }()
+
+	oldDB, oldMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New failed: %v", err)
+	}
+	oldMock.ExpectClose()
+	db.Store(oldDB)
+	defer func() {
+		Close()
+		db.Store(nil)
+		lastConfig, lastDSN = nil, ""
+	}()
+
+	if err := ConnectReplicas("postgres://primary", []DSN{"postgres://replica"}); err != nil {
+		t.Fatalf("ConnectReplicas failed: %v", err)
+	}
+	if err := oldMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("previous pool was not closed: %v", err)
+	}
+	primaryDB := MustGetDB()
+	if max := primaryDB.Stats().MaxOpenConnections; max != 7 {
+		t.Errorf("primary MaxOpenConnections = %d, expected the configured 7", max)
+	}
+	if max := GetReplicaDB().Stats().MaxOpenConnections; max != 7 {
+		t.Errorf("replica MaxOpenConnections = %d, expected the configured 7", max)
+	}
+
+	if err := Reconnect(); err != nil {
+		t.Fatalf("Reconnect failed: %v", err)
+	}
+	if MustGetDB() == primaryDB {
+		t.Error("Reconnect did not swap in a new pool")
+	}
+	if last := opened[len(opened)-1]; last != "postgres://primary" {
+		t.Errorf("Reconnect opened %q, expected the ConnectReplicas primary", last)
+	}
+}
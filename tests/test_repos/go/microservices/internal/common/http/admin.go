@@ -0,0 +1,34 @@
+package http
+
+import (
+	"crypto/subtle"
+	"github.com/gin-gonic/gin"
+	"github.com/greenfuze/go-microservices/internal/common/logger"
+	"github.com/greenfuze/go-microservices/pkg/auth"
+	"net/http"
+)
+
+// AdminAuthMiddleware rejects requests whose "Authorization: Bearer <token>" header does not
+// carry token with a 401 ErrorResponse
+func AdminAuthMiddleware(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		presented, err := auth.ExtractBearerToken(c.GetHeader("Authorization"))
+		if err != nil || token == "" || subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+			RespondError(c, http.StatusUnauthorized, "UNAUTHORIZED", "Admin token required")
+			return
+		}
+		c.Next()
+	}
+}
+
+// RegisterAdminEndpoints mounts the operational endpoints that must not be public, currently
+// GET and PUT /loglevel, behind AdminAuthMiddleware(token)
+// Nothing is mounted when token is empty, so a service without Server.AdminToken exposes none
+func RegisterAdminEndpoints(router *gin.Engine, token string) {
+	if token == "" {
+		logger.Info("Admin token not configured, admin endpoints disabled")
+		return
+	}
+	admin := router.Group("/", AdminAuthMiddleware(token))
+	logger.RegisterLevelEndpoint(admin)
+}
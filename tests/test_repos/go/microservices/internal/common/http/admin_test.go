@@ -0,0 +1,54 @@
+package http
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/greenfuze/go-microservices/internal/common/logger"
+	"go.uber.org/zap/zapcore"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func serveAdmin(router *gin.Engine, method, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/loglevel", strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRegisterAdminEndpointsRequiresToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	RegisterAdminEndpoints(router, "s3cret")
+	defer logger.SetLevel(zapcore.InfoLevel)
+
+	for _, token := range []string{"", "wrong"} {
+		if w := serveAdmin(router, http.MethodPut, token, `{"level":"debug"}`); w.Code != http.StatusUnauthorized {
+			t.Errorf("PUT /loglevel with token %q returned %d, expected 401", token, w.Code)
+		}
+	}
+	if logger.GetLevel() != zapcore.InfoLevel {
+		t.Fatal("unauthenticated request changed the log level")
+	}
+
+	if w := serveAdmin(router, http.MethodPut, "s3cret", `{"level":"debug"}`); w.Code != http.StatusOK {
+		t.Fatalf("authenticated PUT /loglevel returned %d: %s", w.Code, w.Body.String())
+	}
+	if logger.GetLevel() != zapcore.DebugLevel {
+		t.Errorf("level = %s after authenticated PUT, expected debug", logger.GetLevel())
+	}
+}
+
+func TestRegisterAdminEndpointsDisabledWithoutToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	RegisterAdminEndpoints(router, "")
+
+	if w := serveAdmin(router, http.MethodGet, "", ""); w.Code != http.StatusNotFound {
+		t.Errorf("GET /loglevel returned %d without an admin token configured, expected 404", w.Code)
+	}
+}
@@ -0,0 +1,50 @@
+package http
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+)
+
+// BodyLimitMiddleware rejects request bodies larger than maxBytes with 413
+// Use it with router.Use for a global limit or on a route or group to override it there;
+// SetupRouter installs it globally when Server.MaxBodyBytes is configured
+// Requests declaring a larger Content-Length are rejected before the handler runs; for
+// chunked bodies reads fail past the limit and, unless the handler already responded, the
+// middleware answers 413
+func BodyLimitMiddleware(maxBytes int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.ContentLength > maxBytes {
+			abortTooLarge(c)
+			return
+		}
+
+		body := &limitedBody{ReadCloser: http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes)}
+		c.Request.Body = body
+		c.Next()
+
+		if body.exceeded && !c.Writer.Written() {
+			abortTooLarge(c)
+		}
+	}
+}
+
+func abortTooLarge(c *gin.Context) {
+	c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": "request body too large"})
+}
+
+// limitedBody records whether the wrapped http.MaxBytesReader hit its limit
+type limitedBody struct {
+	io.ReadCloser
+	exceeded bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		b.exceeded = true
+	}
+	return n, err
+}
@@ -0,0 +1,52 @@
+package http
+
+import (
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newBodyLimitRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(BodyLimitMiddleware(16))
+	router.POST("/orders", func(c *gin.Context) {
+		if _, err := io.ReadAll(c.Request.Body); err != nil {
+			return
+		}
+		c.Status(http.StatusCreated)
+	})
+	return router
+}
+
+func TestBodyLimitMiddlewareRejectsOversizedBody(t *testing.T) {
+	router := newBodyLimitRouter()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(strings.Repeat("x", 64))))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("oversized body returned %d, expected 413", w.Code)
+	}
+
+	// Without a Content-Length the limit is enforced while the handler reads
+	req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(strings.Repeat("x", 64)))
+	req.ContentLength = -1
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("oversized chunked body returned %d, expected 413", w.Code)
+	}
+}
+
+func TestBodyLimitMiddlewareAllowsSmallBody(t *testing.T) {
+	router := newBodyLimitRouter()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`{"id":1}`)))
+	if w.Code != http.StatusCreated {
+		t.Errorf("small body returned %d, expected 201", w.Code)
+	}
+}
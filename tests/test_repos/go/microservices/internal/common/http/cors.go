@@ -0,0 +1,67 @@
+package http
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// CORS settings applied by CORSMiddleware
+var (
+	CORSAllowedMethods   = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+	CORSAllowedHeaders   = []string{"Authorization", "Content-Type", "X-Request-ID"}
+	CORSAllowCredentials = false
+	CORSMaxAge           = 600 // seconds browsers may cache a preflight response
+)
+
+// CORSMiddleware sets Access-Control-Allow-* headers for requests from allowedOrigins and
+// answers preflight OPTIONS requests with 204
+// "*" allows any origin; browsers reject it on credentialed requests, so combining it with
+// CORSAllowCredentials panics
+func CORSMiddleware(allowedOrigins []string) gin.HandlerFunc {
+	allowAll := false
+	allowed := make(map[string]bool, len(allowedOrigins))
+	for _, origin := range allowedOrigins {
+		if origin == "*" {
+			allowAll = true
+		}
+		allowed[origin] = true
+	}
+	if allowAll && CORSAllowCredentials {
+		panic("http: CORS wildcard origin cannot be used with CORSAllowCredentials")
+	}
+
+	methods := strings.Join(CORSAllowedMethods, ", ")
+	headers := strings.Join(CORSAllowedHeaders, ", ")
+	maxAge := strconv.Itoa(CORSMaxAge)
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+
+		if origin != "" && (allowAll || allowed[origin]) {
+			h := c.Writer.Header()
+			if allowAll {
+				h.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				h.Set("Access-Control-Allow-Origin", origin)
+				h.Add("Vary", "Origin")
+			}
+			if CORSAllowCredentials {
+				h.Set("Access-Control-Allow-Credentials", "true")
+			}
+			if preflight {
+				h.Set("Access-Control-Allow-Methods", methods)
+				h.Set("Access-Control-Allow-Headers", headers)
+				h.Set("Access-Control-Max-Age", maxAge)
+			}
+		}
+
+		if preflight {
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+		c.Next()
+	}
+}
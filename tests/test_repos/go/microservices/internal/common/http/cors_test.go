@@ -0,0 +1,82 @@
+package http
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newCORSRouter(origins []string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(CORSMiddleware(origins))
+	router.GET("/orders", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return router
+}
+
+func TestCORSPreflight(t *testing.T) {
+	router := newCORSRouter([]string{"https://app.example.com"})
+
+	req := httptest.NewRequest(http.MethodOptions, "/orders", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", "GET")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNoContent {
+		t.Errorf("preflight returned %d, expected 204", w.Code)
+	}
+	expected := map[string]string{
+		"Access-Control-Allow-Origin":  "https://app.example.com",
+		"Access-Control-Allow-Methods": "GET, POST, PUT, PATCH, DELETE, OPTIONS",
+		"Access-Control-Allow-Headers": "Authorization, Content-Type, X-Request-ID",
+		"Access-Control-Max-Age":       "600",
+		"Vary":                         "Origin",
+	}
+	for header, value := range expected {
+		if got := w.Header().Get(header); got != value {
+			t.Errorf("%s = %q, expected %q", header, got, value)
+		}
+	}
+}
+
+func TestCORSDisallowedOrigin(t *testing.T) {
+	router := newCORSRouter([]string{"https://app.example.com"})
+
+	req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("Access-Control-Allow-Origin = %q for a disallowed origin", got)
+	}
+}
+
+func TestCORSWildcard(t *testing.T) {
+	router := newCORSRouter([]string{"*"})
+
+	req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+	req.Header.Set("Origin", "https://any.example.com")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("request returned %d", w.Code)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("Access-Control-Allow-Origin = %q, expected *", got)
+	}
+}
+
+func TestCORSWildcardWithCredentialsPanics(t *testing.T) {
+	CORSAllowCredentials = true
+	defer func() {
+		CORSAllowCredentials = false
+		if recover() == nil {
+			t.Error("CORSMiddleware accepted a wildcard origin with credentials")
+		}
+	}()
+	CORSMiddleware([]string{"*"})
+}
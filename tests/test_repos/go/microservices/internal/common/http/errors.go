@@ -0,0 +1,70 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	apperrors "github.com/greenfuze/go-microservices/internal/common/errors"
+	"github.com/greenfuze/go-microservices/internal/common/logger"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// ErrorStatus maps AppError codes to the HTTP status ErrorMiddleware responds with
+// Codes not listed get 500
+var ErrorStatus = map[string]int{
+	"INVALID_INPUT": http.StatusBadRequest,
+	"UNAUTHORIZED":  http.StatusUnauthorized,
+	"FORBIDDEN":     http.StatusForbidden,
+	"NOT_FOUND":     http.StatusNotFound,
+	"CONFLICT":      http.StatusConflict,
+}
+
+// ErrorResponse is the JSON envelope for every error response
+type ErrorResponse struct {
+	Error ErrorBody `json:"error"`
+}
+
+// ErrorBody carries a machine-readable code and a human-readable message
+type ErrorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// RespondError aborts the request with status and an ErrorResponse body
+func RespondError(c *gin.Context, status int, code, message string) {
+	c.AbortWithStatusJSON(status, ErrorResponse{Error: ErrorBody{Code: code, Message: message}})
+}
+
+// ErrorMiddleware renders the first *errors.AppError a handler attached with c.Error,
+// using ErrorStatus for the status, and turns panics into a 500 ErrorResponse
+func ErrorMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			if r := recover(); r != nil {
+				logger.Error("Panic while handling request", zap.String("panic", fmt.Sprint(r)))
+				RespondError(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error")
+			}
+		}()
+
+		c.Next()
+
+		if c.Writer.Written() {
+			return
+		}
+		for _, ginErr := range c.Errors {
+			var appErr *apperrors.AppError
+			if errors.As(ginErr.Err, &appErr) {
+				RespondError(c, statusForCode(appErr.Code), appErr.Code, appErr.Message)
+				return
+			}
+		}
+	}
+}
+
+func statusForCode(code string) int {
+	if status, ok := ErrorStatus[code]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,69 @@
+package http
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	apperrors "github.com/greenfuze/go-microservices/internal/common/errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newErrorRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(ErrorMiddleware())
+	router.GET("/orders/:id", func(c *gin.Context) {
+		c.Error(apperrors.NewAppError("NOT_FOUND", "order does not exist", nil))
+	})
+	router.GET("/panic", func(c *gin.Context) { panic("boom") })
+	router.GET("/invalid", func(c *gin.Context) {
+		RespondError(c, http.StatusBadRequest, "INVALID_INPUT", "amount must be positive")
+	})
+	return router
+}
+
+func decodeError(t *testing.T, w *httptest.ResponseRecorder) ErrorBody {
+	t.Helper()
+	var resp ErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid error envelope %s: %v", w.Body.String(), err)
+	}
+	return resp.Error
+}
+
+func TestErrorMiddlewareRendersAppError(t *testing.T) {
+	w := httptest.NewRecorder()
+	newErrorRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/orders/1", nil))
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status = %d, expected 404", w.Code)
+	}
+	if body := decodeError(t, w); body.Code != "NOT_FOUND" || body.Message != "order does not exist" {
+		t.Errorf("error body = %+v", body)
+	}
+}
+
+func TestErrorMiddlewareRecoversPanics(t *testing.T) {
+	w := httptest.NewRecorder()
+	newErrorRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, expected 500", w.Code)
+	}
+	if body := decodeError(t, w); body.Code != "INTERNAL_ERROR" {
+		t.Errorf("code = %q, expected INTERNAL_ERROR", body.Code)
+	}
+}
+
+func TestRespondError(t *testing.T) {
+	w := httptest.NewRecorder()
+	newErrorRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/invalid", nil))
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, expected 400", w.Code)
+	}
+	if body := decodeError(t, w); body.Code != "INVALID_INPUT" || body.Message != "amount must be positive" {
+		t.Errorf("error body = %+v", body)
+	}
+}
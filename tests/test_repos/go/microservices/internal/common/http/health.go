@@ -0,0 +1,52 @@
+package http
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"path"
+	"reflect"
+	"runtime"
+	"time"
+)
+
+// HealthCheckTimeout bounds all readiness checks run for one /readyz request
+var HealthCheckTimeout = 2 * time.Second
+
+// RegisterHealthEndpoints mounts GET /healthz, which answers 200 while the process is up,
+// and GET /readyz, which answers 200 only when every check passes and otherwise 503 listing
+// the failing checks by function name (e.g. "database.HealthCheck")
+func RegisterHealthEndpoints(router *gin.Engine, checks ...func(context.Context) error) {
+	names := make([]string, len(checks))
+	for i, check := range checks {
+		names[i] = checkName(check)
+	}
+
+	router.GET("/healthz", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	})
+	router.GET("/readyz", func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), HealthCheckTimeout)
+		defer cancel()
+
+		failed := map[string]string{}
+		for i, check := range checks {
+			if err := check(ctx); err != nil {
+				failed[names[i]] = err.Error()
+			}
+		}
+		if len(failed) > 0 {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unavailable", "failed": failed})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"status": "ready"})
+	})
+}
+
+// checkName returns the package-qualified name of fn, e.g. "database.HealthCheck"
+func checkName(fn func(context.Context) error) string {
+	if f := runtime.FuncForPC(reflect.ValueOf(fn).Pointer()); f != nil {
+		return path.Base(f.Name())
+	}
+	return "unknown"
+}
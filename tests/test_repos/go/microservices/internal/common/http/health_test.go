@@ -0,0 +1,51 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func passingCheck(context.Context) error { return nil }
+
+func failingCheck(context.Context) error { return errors.New("connection refused") }
+
+func TestRegisterHealthEndpoints(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	ready := gin.New()
+	RegisterHealthEndpoints(ready, passingCheck)
+	w := httptest.NewRecorder()
+	ready.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("/readyz with passing checks returned %d, expected 200", w.Code)
+	}
+
+	router := gin.New()
+	RegisterHealthEndpoints(router, passingCheck, failingCheck)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("/healthz returned %d, expected 200", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("/readyz with a failing check returned %d, expected 503", w.Code)
+	}
+	var body struct {
+		Failed map[string]string `json:"failed"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid JSON body: %v", err)
+	}
+	if len(body.Failed) != 1 || body.Failed["http.failingCheck"] != "connection refused" {
+		t.Errorf("failed checks = %v, expected only http.failingCheck", body.Failed)
+	}
+}
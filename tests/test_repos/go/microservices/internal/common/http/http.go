@@ -1,32 +1,67 @@
 package http
 
 import (
+	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/greenfuze/go-microservices/internal/common/config"
 	"github.com/greenfuze/go-microservices/internal/common/logger"
+	"github.com/greenfuze/go-microservices/internal/common/metrics"
 )
 
-// SetupRouter sets up a Gin router with middleware
-func SetupRouter() *gin.Engine {
-	cfg := config.GetConfig()
+// MetricsEnabled controls whether SetupRouter installs metrics.MetricsMiddleware
+var MetricsEnabled = true
+
+// SetupRouter sets up a Gin router with middleware from the loaded config, returning an
+// error when config has not been loaded
+func SetupRouter() (*gin.Engine, error) {
+	return SetupRouterWithConfig(config.GetConfig())
+}
+
+// SetupRouterWithConfig sets up a Gin router with middleware for cfg
+// Gin runs in release mode when cfg.Environment is "production", test mode for "test" and
+// debug mode otherwise
+// The router starts from gin.New, so requests are logged and recovered only by this package's
+// middleware rather than also by gin's stdout logger
+func SetupRouterWithConfig(cfg *config.Config) (*gin.Engine, error) {
 	if cfg == nil {
-		logger.Error("Config not loaded")
-		return gin.Default()
+		return nil, errors.New("http: config is nil")
 	}
+	gin.SetMode(ginMode(cfg.Environment))
 
-	router := gin.Default()
+	router := gin.New()
 
 	// Add middleware
+	router.Use(RequestIDMiddleware())
 	router.Use(LoggerMiddleware())
-	router.Use(RecoveryMiddleware())
+	router.Use(StructuredRecoveryMiddleware())
+	if MetricsEnabled {
+		router.Use(metrics.MetricsMiddleware())
+	}
+	if len(cfg.Server.CORSOrigins) > 0 {
+		router.Use(CORSMiddleware(cfg.Server.CORSOrigins))
+	}
+	if cfg.Server.MaxBodyBytes > 0 {
+		router.Use(BodyLimitMiddleware(cfg.Server.MaxBodyBytes))
+	}
+
+	return router, nil
+}
 
-	return router
+func ginMode(environment string) string {
+	switch environment {
+	case "production":
+		return gin.ReleaseMode
+	case "test":
+		return gin.TestMode
+	default:
+		return gin.DebugMode
+	}
 }
 
-// LoggerMiddleware provides request logging
+// LoggerMiddleware provides request logging, including the request id when RequestIDMiddleware ran first
 func LoggerMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		logger.Info("HTTP request")
+		logger.WithContext(c.Request.Context()).Info("HTTP request")
 		c.Next()
 	}
 }
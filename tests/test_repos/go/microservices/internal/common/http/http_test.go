@@ -1,10 +1,64 @@
 package http
 
-import "testing"
+import (
+	"bytes"
+	"github.com/gin-gonic/gin"
+	"github.com/greenfuze/go-microservices/internal/common/config"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
 
 func TestSetupRouter(t *testing.T) {
-	router := SetupRouter()
+	if _, err := config.LoadConfig(); err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	router, err := SetupRouter()
+	if err != nil {
+		t.Fatalf("SetupRouter failed: %v", err)
+	}
 	if router == nil {
 		t.Error("SetupRouter returned nil")
 	}
 }
+
+func TestSetupRouterWithConfigNil(t *testing.T) {
+	if _, err := SetupRouterWithConfig(nil); err == nil {
+		t.Error("SetupRouterWithConfig accepted a nil config")
+	}
+}
+
+func TestSetupRouterWithConfigReleaseMode(t *testing.T) {
+	previousMode, previousWriter := gin.Mode(), gin.DefaultWriter
+	defer func() {
+		gin.SetMode(previousMode)
+		gin.DefaultWriter = previousWriter
+	}()
+	var debugOutput bytes.Buffer
+	gin.DefaultWriter = &debugOutput
+
+	router, err := SetupRouterWithConfig(&config.Config{Environment: "production"})
+	if err != nil {
+		t.Fatalf("SetupRouterWithConfig failed: %v", err)
+	}
+	router.GET("/orders", func(c *gin.Context) {})
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/orders", nil))
+
+	if gin.Mode() != gin.ReleaseMode {
+		t.Errorf("gin mode = %s, expected release", gin.Mode())
+	}
+	if strings.Contains(debugOutput.String(), "[GIN-debug]") {
+		t.Errorf("release mode wrote debug output: %s", debugOutput.String())
+	}
+	if strings.Contains(debugOutput.String(), "[GIN]") {
+		t.Errorf("gin's request logger wrote to stdout: %s", debugOutput.String())
+	}
+
+	if _, err := SetupRouterWithConfig(&config.Config{Environment: config.DefaultEnvironment}); err != nil {
+		t.Fatalf("SetupRouterWithConfig failed: %v", err)
+	}
+	if gin.Mode() != gin.DebugMode {
+		t.Errorf("gin mode = %s for %s, expected debug", gin.Mode(), config.DefaultEnvironment)
+	}
+}
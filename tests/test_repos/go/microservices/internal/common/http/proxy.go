@@ -0,0 +1,40 @@
+package http
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/greenfuze/go-microservices/internal/common/logger"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+)
+
+// ProxyTo forwards requests to the service at target (e.g. "http://localhost:8083"),
+// keeping the request path, query and headers and passing on the request id set by
+// RequestIDMiddleware
+// Requests the upstream cannot serve, including an unparsable target, get a 502 ErrorResponse
+func ProxyTo(target string) gin.HandlerFunc {
+	targetURL, err := url.Parse(target)
+	if err != nil || targetURL.Scheme == "" || targetURL.Host == "" {
+		logger.Error("Invalid proxy target", zap.String("target", target))
+		return func(c *gin.Context) {
+			RespondError(c, http.StatusBadGateway, "BAD_GATEWAY", "Upstream service unavailable")
+		}
+	}
+
+	proxy := httputil.NewSingleHostReverseProxy(targetURL)
+	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		logger.WithContext(r.Context()).Error("Proxy request failed", zap.String("target", target), zap.Error(err))
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusBadGateway)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: ErrorBody{Code: "BAD_GATEWAY", Message: "Upstream service unavailable"}})
+	}
+
+	return func(c *gin.Context) {
+		if id := RequestID(c); id != "" {
+			c.Request.Header.Set(RequestIDHeader, id)
+		}
+		proxy.ServeHTTP(c.Writer, c.Request)
+	}
+}
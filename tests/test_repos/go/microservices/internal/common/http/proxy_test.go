@@ -0,0 +1,74 @@
+package http
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProxyTo(t *testing.T) {
+	var gotPath, gotQuery, gotRequestID, gotAuth string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotQuery = r.URL.Path, r.URL.RawQuery
+		gotRequestID, gotAuth = r.Header.Get(RequestIDHeader), r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer backend.Close()
+
+	gin.SetMode(gin.TestMode)
+	gateway := gin.New()
+	gateway.Use(RequestIDMiddleware())
+	gateway.Any("/orders/*path", ProxyTo(backend.URL))
+
+	// Served over a real connection: ReverseProxy needs the CloseNotifier a recorder lacks
+	server := httptest.NewServer(gateway)
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/orders/42?expand=items", nil)
+	req.Header.Set(RequestIDHeader, "req-123")
+	req.Header.Set("Authorization", "Bearer token")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request through the gateway failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted {
+		t.Errorf("proxied request returned %d, expected the backend's 202", resp.StatusCode)
+	}
+	if gotPath != "/orders/42" || gotQuery != "expand=items" {
+		t.Errorf("backend saw %s?%s, expected /orders/42?expand=items", gotPath, gotQuery)
+	}
+	if gotRequestID != "req-123" {
+		t.Errorf("backend saw request id %q, expected req-123", gotRequestID)
+	}
+	if gotAuth != "Bearer token" {
+		t.Errorf("backend saw Authorization %q", gotAuth)
+	}
+}
+
+func TestProxyToUnavailableBackend(t *testing.T) {
+	backend := httptest.NewServer(http.NotFoundHandler())
+	url := backend.URL
+	backend.Close()
+
+	gin.SetMode(gin.TestMode)
+	gateway := gin.New()
+	gateway.Any("/orders/*path", ProxyTo(url))
+	gateway.Any("/broken/*path", ProxyTo("not a url"))
+
+	server := httptest.NewServer(gateway)
+	defer server.Close()
+
+	for _, path := range []string{"/orders/1", "/broken/1"} {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("request to %s failed: %v", path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadGateway {
+			t.Errorf("%s returned %d, expected 502", path, resp.StatusCode)
+		}
+	}
+}
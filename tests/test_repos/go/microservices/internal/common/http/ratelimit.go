@@ -0,0 +1,66 @@
+package http
+
+import (
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimitClientTTL is how long an idle client's limiter is kept before it is discarded
+var RateLimitClientTTL = 10 * time.Minute
+
+type rateLimitClient struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// RateLimitMiddleware limits each client IP to rps requests per second with bursts of up to
+// burst, answering 429 with a Retry-After header once the limit is exceeded
+// Limiters for clients idle longer than RateLimitClientTTL are dropped as requests arrive
+func RateLimitMiddleware(rps int, burst int) gin.HandlerFunc {
+	var (
+		mu          sync.Mutex
+		clients     = make(map[string]*rateLimitClient)
+		lastCleanup = time.Now()
+	)
+
+	return func(c *gin.Context) {
+		now := time.Now()
+		ip := c.ClientIP()
+
+		mu.Lock()
+		if now.Sub(lastCleanup) > RateLimitClientTTL {
+			for key, client := range clients {
+				if now.Sub(client.lastSeen) > RateLimitClientTTL {
+					delete(clients, key)
+				}
+			}
+			lastCleanup = now
+		}
+		client, ok := clients[ip]
+		if !ok {
+			client = &rateLimitClient{limiter: rate.NewLimiter(rate.Limit(rps), burst)}
+			clients[ip] = client
+		}
+		client.lastSeen = now
+		reservation := client.limiter.ReserveN(now, 1)
+		mu.Unlock()
+
+		if !reservation.OK() {
+			c.Header("Retry-After", "1")
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded"})
+			return
+		}
+		if delay := reservation.DelayFrom(now); delay > 0 {
+			reservation.CancelAt(now)
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded"})
+			return
+		}
+		c.Next()
+	}
+}
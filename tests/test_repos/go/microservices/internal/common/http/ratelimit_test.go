@@ -0,0 +1,42 @@
+package http
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRateLimitMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const rps = 5
+	router := gin.New()
+	router.Use(RateLimitMiddleware(rps, rps))
+	router.GET("/orders", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	request := func(ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+		req.RemoteAddr = ip + ":1234"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	for i := 0; i < rps; i++ {
+		if w := request("10.0.0.1"); w.Code != http.StatusOK {
+			t.Fatalf("request %d returned %d, expected 200", i+1, w.Code)
+		}
+	}
+
+	w := request("10.0.0.1")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("request %d returned %d, expected 429", rps+1, w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("429 response missing Retry-After")
+	}
+
+	if w := request("10.0.0.2"); w.Code != http.StatusOK {
+		t.Errorf("another client was throttled with %d", w.Code)
+	}
+}
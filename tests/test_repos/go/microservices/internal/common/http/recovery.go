@@ -0,0 +1,39 @@
+package http
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/greenfuze/go-microservices/internal/common/logger"
+	"github.com/greenfuze/go-microservices/internal/common/metrics"
+	"go.uber.org/zap"
+	"net/http"
+	"runtime/debug"
+)
+
+// StructuredRecoveryMiddleware recovers panics, logs the panic value and stack through the
+// logger package, records the 500 with metrics.RecordStatus and responds with an ErrorResponse
+// It sits outside MetricsMiddleware in SetupRouter, so a recovered panic is counted once
+func StructuredRecoveryMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			r := recover()
+			if r == nil {
+				return
+			}
+
+			endpoint := c.FullPath()
+			if endpoint == "" {
+				endpoint = "unmatched"
+			}
+			logger.WithContext(c.Request.Context()).Error("Panic recovered",
+				zap.String("panic", fmt.Sprint(r)),
+				zap.String("method", c.Request.Method),
+				zap.String("endpoint", endpoint),
+				zap.ByteString("stack", debug.Stack()))
+			metrics.RecordStatus(c.Request.Method, endpoint, http.StatusInternalServerError)
+			RespondError(c, http.StatusInternalServerError, "INTERNAL_ERROR", "Internal server error")
+		}()
+
+		c.Next()
+	}
+}
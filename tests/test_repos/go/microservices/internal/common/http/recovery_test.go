@@ -0,0 +1,49 @@
+package http
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/greenfuze/go-microservices/internal/common/metrics"
+	testhelper "github.com/greenfuze/go-microservices/internal/common/test-helper"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestStructuredRecoveryMiddleware(t *testing.T) {
+	logs := testhelper.NewTestLogger(t)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(StructuredRecoveryMiddleware())
+	router.GET("/orders/:id", func(c *gin.Context) { panic("boom") })
+
+	status := metrics.ResponseStatus.WithLabelValues(http.MethodGet, "/orders/:id", "5xx")
+	before := testutil.ToFloat64(status)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/orders/1", nil))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, expected 500", w.Code)
+	}
+	if body := decodeError(t, w); body.Code != "INTERNAL_ERROR" {
+		t.Errorf("code = %q, expected INTERNAL_ERROR", body.Code)
+	}
+	if got := testutil.ToFloat64(status) - before; got != 1 {
+		t.Errorf("recorded %v 5xx responses, expected 1", got)
+	}
+
+	entries := logs.FilterMessage("Panic recovered").All()
+	if len(entries) != 1 {
+		t.Fatalf("logged %d panic entries, expected 1", len(entries))
+	}
+	fields := entries[0].ContextMap()
+	if fields["panic"] != "boom" {
+		t.Errorf("panic field = %v, expected boom", fields["panic"])
+	}
+	if stack, _ := fields["stack"].(string); !strings.Contains(stack, "goroutine") {
+		t.Errorf("stack field does not hold a stack trace: %v", fields["stack"])
+	}
+}
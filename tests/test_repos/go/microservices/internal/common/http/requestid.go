@@ -0,0 +1,36 @@
+package http
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/greenfuze/go-microservices/internal/common/logger"
+)
+
+// RequestIDHeader is the header carrying a request's correlation id
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDContextKey is the gin context key RequestIDMiddleware stores the id under
+const requestIDContextKey = "request_id"
+
+// RequestIDMiddleware tags every request with the incoming X-Request-ID, or a new UUID when
+// absent, and echoes it on the response
+// The id is also stored on the request context, so logger.WithContext(c.Request.Context())
+// includes it in log lines
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if id == "" {
+			id = uuid.NewString()
+		}
+
+		c.Set(requestIDContextKey, id)
+		c.Request = c.Request.WithContext(logger.ContextWithRequestID(c.Request.Context(), id))
+		c.Header(RequestIDHeader, id)
+		c.Next()
+	}
+}
+
+// RequestID returns the id assigned by RequestIDMiddleware, or "" if it did not run
+func RequestID(c *gin.Context) string {
+	return c.GetString(requestIDContextKey)
+}
@@ -0,0 +1,50 @@
+package http
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/greenfuze/go-microservices/internal/common/logger"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestIDMiddlewareGeneratesID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestIDMiddleware())
+
+	var handlerID, contextID string
+	router.GET("/orders", func(c *gin.Context) {
+		handlerID = RequestID(c)
+		contextID, _ = logger.RequestIDFromContext(c.Request.Context())
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/orders", nil))
+
+	id := w.Header().Get(RequestIDHeader)
+	if _, err := uuid.Parse(id); err != nil {
+		t.Fatalf("response %s = %q, expected a generated UUID", RequestIDHeader, id)
+	}
+	if handlerID != id || contextID != id {
+		t.Errorf("handler saw %q and context %q, expected %q", handlerID, contextID, id)
+	}
+}
+
+func TestRequestIDMiddlewareKeepsIncomingID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestIDMiddleware())
+	router.GET("/orders", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+	req.Header.Set(RequestIDHeader, "req-123")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if got := w.Header().Get(RequestIDHeader); got != "req-123" {
+		t.Errorf("response %s = %q, expected req-123", RequestIDHeader, got)
+	}
+}
@@ -0,0 +1,55 @@
+package http
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func serveWithSecurityHeaders(opts ...SecurityHeaderOption) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(SecurityHeadersMiddleware(opts...))
+	router.GET("/page", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/page", nil))
+	return w
+}
+
+func TestSecurityHeadersMiddlewareDefaults(t *testing.T) {
+	w := serveWithSecurityHeaders()
+
+	for header, expected := range map[string]string{
+		"X-Content-Type-Options":  "nosniff",
+		"X-Frame-Options":         "DENY",
+		"Referrer-Policy":         "strict-origin-when-cross-origin",
+		"Content-Security-Policy": "default-src 'self'",
+	} {
+		if got := w.Header().Get(header); got != expected {
+			t.Errorf("%s = %q, expected %q", header, got, expected)
+		}
+	}
+}
+
+func TestSecurityHeadersMiddlewareOverrides(t *testing.T) {
+	w := serveWithSecurityHeaders(
+		WithContentSecurityPolicy("default-src 'self'; img-src *"),
+		WithSecurityHeader(HeaderFrameOptions, "SAMEORIGIN"),
+		WithSecurityHeader(HeaderReferrerPolicy, ""),
+	)
+
+	if got := w.Header().Get(HeaderContentSecurityPolicy); got != "default-src 'self'; img-src *" {
+		t.Errorf("Content-Security-Policy = %q, expected the configured policy", got)
+	}
+	if got := w.Header().Get(HeaderFrameOptions); got != "SAMEORIGIN" {
+		t.Errorf("X-Frame-Options = %q, expected SAMEORIGIN", got)
+	}
+	if _, ok := w.Header()[HeaderReferrerPolicy]; ok {
+		t.Error("Referrer-Policy was set despite being overridden with an empty value")
+	}
+	if got := w.Header().Get(HeaderContentTypeOptions); got != "nosniff" {
+		t.Errorf("X-Content-Type-Options = %q, expected the default nosniff", got)
+	}
+}
@@ -0,0 +1,92 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/greenfuze/go-microservices/internal/common/logger"
+	"go.uber.org/zap"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// DefaultShutdownTimeout is how long services give in-flight requests to finish on shutdown
+const DefaultShutdownTimeout = 10 * time.Second
+
+// notifyShutdown returns a context canceled on SIGINT or SIGTERM; tests replace it
+var notifyShutdown = func(ctx context.Context) (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+}
+
+// RunGraceful serves router on addr until SIGINT or SIGTERM, then stops accepting connections
+// and waits up to timeout for in-flight requests before returning
+// It returns nil after a clean shutdown and the listen error if the server fails to start
+func RunGraceful(router *gin.Engine, addr string, timeout time.Duration) error {
+	server := &http.Server{Addr: addr, Handler: router}
+	return serveGraceful(server, timeout, server.ListenAndServe)
+}
+
+// RunTLS is RunGraceful over HTTPS using the certificate and key in certFile and keyFile,
+// waiting up to DefaultShutdownTimeout for in-flight requests on shutdown
+func RunTLS(router *gin.Engine, addr, certFile, keyFile string) error {
+	server := &http.Server{Addr: addr, Handler: router, TLSConfig: &tls.Config{MinVersion: tls.VersionTLS12}}
+	return serveGraceful(server, DefaultShutdownTimeout, func() error {
+		return server.ListenAndServeTLS(certFile, keyFile)
+	})
+}
+
+// RunMTLS is RunTLS that also requires every client to present a certificate signed by a CA
+// in the PEM bundle caFile
+func RunMTLS(router *gin.Engine, addr, certFile, keyFile, caFile string) error {
+	caPEM, err := os.ReadFile(caFile)
+	if err != nil {
+		return err
+	}
+	clientCAs := x509.NewCertPool()
+	if !clientCAs.AppendCertsFromPEM(caPEM) {
+		return fmt.Errorf("no certificates found in %s", caFile)
+	}
+
+	server := &http.Server{Addr: addr, Handler: router, TLSConfig: &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  clientCAs,
+	}}
+	return serveGraceful(server, DefaultShutdownTimeout, func() error {
+		return server.ListenAndServeTLS(certFile, keyFile)
+	})
+}
+
+func serveGraceful(server *http.Server, timeout time.Duration, serve func() error) error {
+	ctx, stop := notifyShutdown(context.Background())
+	defer stop()
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- serve()
+	}()
+
+	select {
+	case err := <-errs:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	case <-ctx.Done():
+	}
+
+	logger.Info("Shutting down HTTP server", zap.Duration("timeout", timeout))
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	logger.Info("HTTP server stopped")
+	return nil
+}
@@ -0,0 +1,232 @@
+package http
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/gin-gonic/gin"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// freeAddr returns a loopback address with a port that is free at the time of the call
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer ln.Close()
+	return ln.Addr().String()
+}
+
+// fakeShutdownSignal replaces the SIGINT/SIGTERM context with one the returned func cancels
+func fakeShutdownSignal(t *testing.T) context.CancelFunc {
+	t.Helper()
+	ctx, trigger := context.WithCancel(context.Background())
+	previous := notifyShutdown
+	notifyShutdown = func(context.Context) (context.Context, context.CancelFunc) {
+		return ctx, func() {}
+	}
+	t.Cleanup(func() {
+		notifyShutdown = previous
+		trigger()
+	})
+	return trigger
+}
+
+// waitForServer polls url until it answers or the deadline passes
+func waitForServer(t *testing.T, client *http.Client, url string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if resp, err := client.Get(url); err == nil {
+			resp.Body.Close()
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("server at %s never became ready", url)
+}
+
+func TestRunGracefulShutsDown(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	trigger := fakeShutdownSignal(t)
+	addr := freeAddr(t)
+
+	started := make(chan struct{})
+	router := gin.New()
+	router.GET("/ping", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/slow", func(c *gin.Context) {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		c.Status(http.StatusOK)
+	})
+
+	result := make(chan error, 1)
+	go func() { result <- RunGraceful(router, addr, 5*time.Second) }()
+	waitForServer(t, http.DefaultClient, "http://"+addr+"/ping")
+
+	inFlight := make(chan int, 1)
+	go func() {
+		resp, err := http.Get("http://" + addr + "/slow")
+		if err != nil {
+			inFlight <- 0
+			return
+		}
+		resp.Body.Close()
+		inFlight <- resp.StatusCode
+	}()
+	<-started
+	trigger()
+
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Fatalf("RunGraceful returned %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("RunGraceful did not return after the shutdown signal")
+	}
+	if status := <-inFlight; status != http.StatusOK {
+		t.Errorf("in-flight request finished with %d, expected 200", status)
+	}
+	if _, err := http.Get("http://" + addr + "/ping"); err == nil {
+		t.Error("server still accepting connections after shutdown")
+	}
+}
+
+func TestRunGracefulListenError(t *testing.T) {
+	fakeShutdownSignal(t)
+	if err := RunGraceful(gin.New(), "256.0.0.1:80", time.Second); err == nil {
+		t.Error("RunGraceful returned nil for an unusable address")
+	}
+}
+
+// writeSelfSignedCert writes a self-signed certificate for 127.0.0.1, usable as server
+// certificate, client certificate and CA, and returns the cert and key paths
+func writeSelfSignedCert(t *testing.T) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate failed: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalECPrivateKey failed: %v", err)
+	}
+
+	dir := t.TempDir()
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	return certFile, keyFile
+}
+
+// tlsClient trusts the certificate in certFile and, when withClientCert is set, presents it
+func tlsClient(t *testing.T, certFile, keyFile string, withClientCert bool) *http.Client {
+	t.Helper()
+	pemData, err := os.ReadFile(certFile)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(pemData)
+	config := &tls.Config{RootCAs: roots}
+	if withClientCert {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			t.Fatalf("LoadX509KeyPair failed: %v", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: config}, Timeout: 5 * time.Second}
+}
+
+func pingRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/ping", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return router
+}
+
+func TestRunTLS(t *testing.T) {
+	trigger := fakeShutdownSignal(t)
+	certFile, keyFile := writeSelfSignedCert(t)
+	addr := freeAddr(t)
+
+	result := make(chan error, 1)
+	go func() { result <- RunTLS(pingRouter(), addr, certFile, keyFile) }()
+
+	client := tlsClient(t, certFile, keyFile, false)
+	waitForServer(t, client, "https://"+addr+"/ping")
+	resp, err := client.Get("https://" + addr + "/ping")
+	if err != nil {
+		t.Fatalf("HTTPS request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("HTTPS request returned %d", resp.StatusCode)
+	}
+
+	trigger()
+	if err := <-result; err != nil {
+		t.Errorf("RunTLS returned %v", err)
+	}
+}
+
+func TestRunMTLSRequiresClientCert(t *testing.T) {
+	trigger := fakeShutdownSignal(t)
+	certFile, keyFile := writeSelfSignedCert(t)
+	addr := freeAddr(t)
+
+	result := make(chan error, 1)
+	go func() { result <- RunMTLS(pingRouter(), addr, certFile, keyFile, certFile) }()
+
+	client := tlsClient(t, certFile, keyFile, true)
+	waitForServer(t, client, "https://"+addr+"/ping")
+	resp, err := client.Get("https://" + addr + "/ping")
+	if err != nil {
+		t.Fatalf("mTLS request with a client certificate failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if _, err := tlsClient(t, certFile, keyFile, false).Get("https://" + addr + "/ping"); err == nil {
+		t.Error("mTLS server accepted a client without a certificate")
+	}
+
+	trigger()
+	if err := <-result; err != nil {
+		t.Errorf("RunMTLS returned %v", err)
+	}
+}
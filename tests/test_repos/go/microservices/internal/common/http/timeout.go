@@ -0,0 +1,135 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/greenfuze/go-microservices/internal/common/logger"
+	"go.uber.org/zap"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// TimeoutMiddleware gives the rest of the chain d to respond, answering 503 if it does not
+// The chain runs with a context deadline and writes into a buffer that is only copied to the
+// client if it finishes in time, so late writes never reach the connection
+// The 503 is flushed as soon as d passes, but the middleware still waits for the chain to
+// return before releasing the gin context; handlers must watch c.Request.Context(), since
+// one that ignores it keeps its goroutine and resources busy until it finishes
+func TimeoutMiddleware(d time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), d)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		original := c.Writer
+		buffered := &timeoutWriter{ResponseWriter: original, header: make(http.Header), status: http.StatusOK}
+		c.Writer = buffered
+
+		done := make(chan struct{})
+		panicked := make(chan recoveredPanic, 1)
+		go func() {
+			defer func() {
+				if p := recover(); p != nil {
+					panicked <- recoveredPanic{value: p, stack: debug.Stack()}
+				}
+			}()
+			c.Next()
+			close(done)
+		}()
+
+		select {
+		case p := <-panicked:
+			c.Writer = original
+			panic(p.value)
+		case <-done:
+			buffered.mu.Lock()
+			defer buffered.mu.Unlock()
+			c.Writer = original
+			for key, values := range buffered.header {
+				original.Header()[key] = values
+			}
+			original.WriteHeader(buffered.status)
+			original.Write(buffered.body.Bytes())
+		case <-ctx.Done():
+			buffered.mu.Lock()
+			buffered.timedOut = true
+			buffered.mu.Unlock()
+			original.Header().Set("Content-Type", "application/json; charset=utf-8")
+			original.WriteHeader(http.StatusServiceUnavailable)
+			original.Write([]byte(`{"error":"request timed out"}`))
+			original.Flush()
+
+			// gin reuses the context once this returns, so wait for the chain to let go of it
+			// A panic now cannot change the response already sent, so it is only logged
+			select {
+			case <-done:
+			case p := <-panicked:
+				logger.WithContext(ctx).Error("Panic after request timed out",
+					zap.String("panic", fmt.Sprint(p.value)),
+					zap.String("method", c.Request.Method),
+					zap.String("path", c.Request.URL.Path),
+					zap.ByteString("stack", p.stack))
+			}
+			c.Writer = original
+		}
+	}
+}
+
+// recoveredPanic carries a panic out of the chain goroutine together with its stack
+type recoveredPanic struct {
+	value interface{}
+	stack []byte
+}
+
+// timeoutWriter buffers a response until TimeoutMiddleware decides whether to send it
+type timeoutWriter struct {
+	gin.ResponseWriter
+	mu       sync.Mutex
+	header   http.Header
+	body     bytes.Buffer
+	status   int
+	timedOut bool
+}
+
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *timeoutWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	return w.body.Write(data)
+}
+
+func (w *timeoutWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *timeoutWriter) WriteHeader(status int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.timedOut {
+		w.status = status
+	}
+}
+
+func (w *timeoutWriter) WriteHeaderNow() {}
+
+func (w *timeoutWriter) Status() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.status
+}
+
+func (w *timeoutWriter) Written() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.body.Len() > 0
+}
@@ -0,0 +1,90 @@
+package http
+
+import (
+	"github.com/gin-gonic/gin"
+	testhelper "github.com/greenfuze/go-microservices/internal/common/test-helper"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newTimeoutRouter(d time.Duration) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(TimeoutMiddleware(d))
+	router.GET("/slow", func(c *gin.Context) {
+		select {
+		case <-time.After(time.Second):
+			c.JSON(http.StatusOK, gin.H{"status": "done"})
+		case <-c.Request.Context().Done():
+		}
+	})
+	router.GET("/fast", func(c *gin.Context) {
+		c.Header("X-Order", "order-1")
+		c.JSON(http.StatusCreated, gin.H{"status": "created"})
+	})
+	return router
+}
+
+func TestTimeoutMiddlewareTimesOut(t *testing.T) {
+	router := newTimeoutRouter(50 * time.Millisecond)
+
+	w := httptest.NewRecorder()
+	start := time.Now()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("slow handler returned %d, expected 503", w.Code)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("request took %v, expected it to end at the timeout", elapsed)
+	}
+}
+
+func TestTimeoutMiddlewarePassesFastResponses(t *testing.T) {
+	router := newTimeoutRouter(time.Second)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/fast", nil))
+
+	if w.Code != http.StatusCreated {
+		t.Errorf("fast handler returned %d, expected 201", w.Code)
+	}
+	if w.Header().Get("X-Order") != "order-1" {
+		t.Error("handler header not copied to the response")
+	}
+	if w.Body.String() != `{"status":"created"}` {
+		t.Errorf("body = %s", w.Body.String())
+	}
+}
+
+func TestTimeoutMiddlewareLogsLatePanic(t *testing.T) {
+	logs := testhelper.NewTestLogger(t)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(TimeoutMiddleware(20 * time.Millisecond))
+	router.GET("/crash", func(c *gin.Context) {
+		<-c.Request.Context().Done()
+		panic("late failure")
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/crash", nil))
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, expected the 503 sent at the timeout", w.Code)
+	}
+	entries := logs.FilterMessage("Panic after request timed out").All()
+	if len(entries) != 1 {
+		t.Fatalf("logged %d late panics, expected 1", len(entries))
+	}
+	fields := entries[0].ContextMap()
+	if fields["panic"] != "late failure" {
+		t.Errorf("panic field = %v, expected late failure", fields["panic"])
+	}
+	if stack, _ := fields["stack"].(string); stack == "" {
+		t.Error("stack field missing")
+	}
+}
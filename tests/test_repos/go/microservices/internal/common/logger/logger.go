@@ -1,16 +1,207 @@
 package logger
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	apperrors "github.com/greenfuze/go-microservices/internal/common/errors"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"syscall"
 )
 
-var log *zap.Logger
+var (
+	log   *zap.Logger
+	level = zap.NewAtomicLevelAt(zapcore.InfoLevel)
+
+	// base is log without the fields from SetGlobalFields
+	base         *zap.Logger
+	globalFields []zap.Field
+)
+
+// buildConfig builds a logger from a zap config; tests replace it to simulate failures
+var buildConfig = func(config zap.Config, opts ...zap.Option) (*zap.Logger, error) {
+	return config.Build(opts...)
+}
 
 func init() {
+	base = newDefaultLogger()
+	log = base
+}
+
+// newDefaultLogger builds the production JSON logger, falling back to zap.NewExample with a
+// warning on stderr if that fails, so GetLogger never returns nil
+func newDefaultLogger() *zap.Logger {
 	config := zap.NewProductionConfig()
-	config.Level = zap.NewAtomicLevelAt(zapcore.InfoLevel)
-	log, _ = config.Build()
+	config.Level = level
+	built, err := buildConfig(config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "logger: failed to build production logger, using fallback: %v\n", err)
+		return zap.NewExample()
+	}
+	return built
+}
+
+// Options configures the package logger built by Init
+type Options struct {
+	// Encoding is "json" or "console"; empty uses LOG_FORMAT, then "json"
+	Encoding string
+	// Level is the minimum level, e.g. "debug"; empty uses LOG_LEVEL, then "info"
+	Level string
+	// OutputPaths are zap sink URLs or file paths; empty logs to stderr unless File is set
+	OutputPaths []string
+
+	// File, when set, adds a log file rotated by size through lumberjack
+	File string
+	// MaxSize is the size in megabytes at which File is rotated; 0 uses lumberjack's 100
+	MaxSize int
+	// MaxBackups is how many rotated files to keep; 0 keeps all
+	MaxBackups int
+	// MaxAge is how many days to keep rotated files; 0 keeps them regardless of age
+	MaxAge int
+
+	// Sampling caps repetitive entries: per second, the first Initial entries with the same
+	// level and message are logged, then every Thereafter-th; nil uses the production
+	// sampler (100 then every 100th)
+	Sampling *zap.SamplingConfig
+}
+
+// Init replaces the package logger with one built from opts
+// The logger built at package init, JSON at info level on stderr, is kept if Init fails
+func Init(opts Options) error {
+	encoding := opts.Encoding
+	if encoding == "" {
+		encoding = os.Getenv("LOG_FORMAT")
+	}
+	levelText := opts.Level
+	if levelText == "" {
+		levelText = os.Getenv("LOG_LEVEL")
+	}
+
+	config := zap.NewProductionConfig()
+	switch encoding {
+	case "", "json":
+	case "console":
+		config.Encoding = "console"
+		config.EncoderConfig = zap.NewDevelopmentEncoderConfig()
+	default:
+		return fmt.Errorf("unknown log encoding %q", encoding)
+	}
+	if opts.Sampling != nil {
+		config.Sampling = opts.Sampling
+	}
+	if len(opts.OutputPaths) > 0 || opts.File != "" {
+		config.OutputPaths = opts.OutputPaths
+	}
+	if opts.File != "" {
+		sink, err := rotatingSinkURL(opts)
+		if err != nil {
+			return err
+		}
+		config.OutputPaths = append(config.OutputPaths, sink)
+	}
+
+	minLevel := zapcore.InfoLevel
+	if levelText != "" {
+		parsed, err := zapcore.ParseLevel(levelText)
+		if err != nil {
+			return err
+		}
+		minLevel = parsed
+	}
+	config.Level = level
+
+	built, err := buildConfig(config)
+	if err != nil {
+		return err
+	}
+	level.SetLevel(minLevel)
+	base = built
+	log = base.With(globalFields...)
+	return nil
+}
+
+// rotatingSinkScheme is the zap sink scheme that writes through lumberjack
+const rotatingSinkScheme = "lumberjack"
+
+var registerRotatingSink sync.Once
+
+// rotatingSink adapts a lumberjack.Logger to zap.Sink; its writes are unbuffered, so Sync is a no-op
+type rotatingSink struct {
+	*lumberjack.Logger
+}
+
+func (rotatingSink) Sync() error {
+	return nil
+}
+
+// rotatingSinkURL registers the lumberjack sink with zap once and returns the URL for opts.File
+func rotatingSinkURL(opts Options) (string, error) {
+	var err error
+	registerRotatingSink.Do(func() {
+		err = zap.RegisterSink(rotatingSinkScheme, func(u *url.URL) (zap.Sink, error) {
+			query := u.Query()
+			maxSize, _ := strconv.Atoi(query.Get("maxsize"))
+			maxBackups, _ := strconv.Atoi(query.Get("maxbackups"))
+			maxAge, _ := strconv.Atoi(query.Get("maxage"))
+			return rotatingSink{&lumberjack.Logger{
+				Filename:   u.Path,
+				MaxSize:    maxSize,
+				MaxBackups: maxBackups,
+				MaxAge:     maxAge,
+			}}, nil
+		})
+	})
+	if err != nil {
+		return "", err
+	}
+
+	path, err := filepath.Abs(opts.File)
+	if err != nil {
+		return "", err
+	}
+	query := url.Values{}
+	query.Set("maxsize", strconv.Itoa(opts.MaxSize))
+	query.Set("maxbackups", strconv.Itoa(opts.MaxBackups))
+	query.Set("maxage", strconv.Itoa(opts.MaxAge))
+	sink := url.URL{Scheme: rotatingSinkScheme, Path: filepath.ToSlash(path), RawQuery: query.Encode()}
+	return sink.String(), nil
+}
+
+// AtomicLevel returns the level shared by the package logger; changing it takes effect immediately
+func AtomicLevel() zap.AtomicLevel {
+	return level
+}
+
+// SetLevel changes the minimum level logged at runtime
+func SetLevel(l zapcore.Level) {
+	level.SetLevel(l)
+}
+
+// GetLevel returns the minimum level currently logged
+func GetLevel() zapcore.Level {
+	return level.Level()
+}
+
+// LevelHandler reports the current level on GET and changes it on PUT, using zap's level
+// handler: the body is JSON such as {"level":"debug"}, or a level=debug form value
+func LevelHandler() gin.HandlerFunc {
+	return gin.WrapH(level)
+}
+
+// RegisterLevelEndpoint mounts LevelHandler at GET and PUT /loglevel on routes
+// The endpoint lets callers change the level, so mount it on a group that authenticates them,
+// as http.RegisterAdminEndpoints does, rather than on the public router
+func RegisterLevelEndpoint(router gin.IRoutes) {
+	router.GET("/loglevel", LevelHandler())
+	router.PUT("/loglevel", LevelHandler())
 }
 
 // GetLogger returns the logger instance
@@ -18,11 +209,80 @@ func GetLogger() *zap.Logger {
 	return log
 }
 
+// SetLogger replaces the package logger, adding the global fields to l, and returns a func
+// that restores the previous logger
+// Tests should call the restore func on cleanup, or use testhelper.NewTestLogger
+func SetLogger(l *zap.Logger) (restore func()) {
+	previousBase, previousLog := base, log
+	base = l
+	log = base.With(globalFields...)
+	return func() {
+		base, log = previousBase, previousLog
+	}
+}
+
+// WithFields returns a logger that adds fields to every message, for callers that repeat
+// the same context (module, component) on many log calls
+func WithFields(fields ...zap.Field) *zap.Logger {
+	return log.With(fields...)
+}
+
+// SetGlobalFields replaces the fields added to every message from the package logger,
+// e.g. the service name; they are kept when Init rebuilds the logger
+func SetGlobalFields(fields ...zap.Field) {
+	globalFields = fields
+	log = base.With(fields...)
+}
+
+type requestIDKey struct{}
+
+// ContextWithRequestID returns a copy of ctx carrying id for WithContext to attach to log lines
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request id stored by ContextWithRequestID
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDKey{}).(string)
+	return id, ok && id != ""
+}
+
+// WithContext returns a logger that adds a request_id field when ctx carries one
+func WithContext(ctx context.Context) *zap.Logger {
+	if id, ok := RequestIDFromContext(ctx); ok {
+		return log.With(zap.String("request_id", id))
+	}
+	return log
+}
+
+// Fields converts alternating key/value pairs into zap fields, e.g. Fields("user_id", id, "error", err)
+// Non-string keys are formatted with fmt.Sprint and a trailing key without a value maps to nil
+func Fields(keysAndValues ...interface{}) []zap.Field {
+	fields := make([]zap.Field, 0, (len(keysAndValues)+1)/2)
+	for i := 0; i < len(keysAndValues); i += 2 {
+		key, ok := keysAndValues[i].(string)
+		if !ok {
+			key = fmt.Sprint(keysAndValues[i])
+		}
+		var value interface{}
+		if i+1 < len(keysAndValues) {
+			value = keysAndValues[i+1]
+		}
+		fields = append(fields, zap.Any(key, value))
+	}
+	return fields
+}
+
 // Info logs an info message
 func Info(msg string, fields ...zap.Field) {
 	log.Info(msg, fields...)
 }
 
+// Warn logs a warning message
+func Warn(msg string, fields ...zap.Field) {
+	log.Warn(msg, fields...)
+}
+
 // Error logs an error message
 func Error(msg string, fields ...zap.Field) {
 	log.Error(msg, fields...)
@@ -32,3 +292,35 @@ func Error(msg string, fields ...zap.Field) {
 func Debug(msg string, fields ...zap.Field) {
 	log.Debug(msg, fields...)
 }
+
+// Fatal logs a fatal message and then exits the process with status 1
+func Fatal(msg string, fields ...zap.Field) {
+	log.Fatal(msg, fields...)
+}
+
+// LogError logs err at error level with msg
+// An *errors.AppError anywhere in err's chain contributes "code" and "message" fields, with
+// its cause under "error"; any other error is logged under "error"
+func LogError(err error, msg string, fields ...zap.Field) {
+	var appErr *apperrors.AppError
+	if errors.As(err, &appErr) {
+		fields = append(fields, zap.String("code", appErr.Code), zap.String("message", appErr.Message))
+		if appErr.Err != nil {
+			fields = append(fields, zap.Error(appErr.Err))
+		}
+	} else {
+		fields = append(fields, zap.Error(err))
+	}
+	log.Error(msg, fields...)
+}
+
+// Sync flushes buffered log entries; services defer it in main so the tail is not lost on exit
+// Syncing a terminal or pipe such as os.Stdout or os.Stderr fails with EINVAL or ENOTTY on
+// Linux and macOS even though nothing was lost, so those errors are ignored
+func Sync() error {
+	err := log.Sync()
+	if errors.Is(err, syscall.EINVAL) || errors.Is(err, syscall.ENOTTY) {
+		return nil
+	}
+	return err
+}
@@ -1,6 +1,34 @@
 package logger
 
-import "testing"
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	apperrors "github.com/greenfuze/go-microservices/internal/common/errors"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// restoreLoggerState snapshots the package logger, its base, the global fields and the level,
+// restoring them on cleanup so tests that call Init do not leak their sink or level
+func restoreLoggerState(t *testing.T) {
+	t.Helper()
+	previousLog, previousBase, previousFields := log, base, globalFields
+	previousLevel := level.Level()
+	t.Cleanup(func() {
+		log, base, globalFields = previousLog, previousBase, previousFields
+		level.SetLevel(previousLevel)
+	})
+}
 
 func TestGetLogger(t *testing.T) {
 	log := GetLogger()
@@ -12,3 +40,334 @@ func TestGetLogger(t *testing.T) {
 func TestInfo(t *testing.T) {
 	Info("test message")
 }
+
+func TestSetLevel(t *testing.T) {
+	core, logs := observer.New(AtomicLevel())
+	previous := log
+	log = zap.New(core)
+	defer func() {
+		log = previous
+		SetLevel(zapcore.InfoLevel)
+	}()
+
+	Debug("hidden")
+	if logs.Len() != 0 {
+		t.Fatalf("Debug emitted %d entries at InfoLevel, expected none", logs.Len())
+	}
+
+	SetLevel(zapcore.DebugLevel)
+	if GetLevel() != zapcore.DebugLevel {
+		t.Errorf("GetLevel = %v, expected debug", GetLevel())
+	}
+	Debug("visible")
+	if logs.FilterMessage("visible").Len() != 1 {
+		t.Error("Debug not emitted after SetLevel(DebugLevel)")
+	}
+}
+
+func TestFields(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	err := errors.New("connection refused")
+
+	zap.New(core).Info("mixed", Fields("user", "alice", "attempts", 3, "ratio", 0.5, "error", err, 42, true, "dangling")...)
+
+	fields := logs.All()[0].ContextMap()
+	expected := map[string]interface{}{
+		"user":     "alice",
+		"attempts": int64(3),
+		"ratio":    0.5,
+		"error":    "connection refused",
+		"42":       true,
+		"dangling": nil,
+	}
+	if len(fields) != len(expected) {
+		t.Fatalf("got %d fields (%v), expected %d", len(fields), fields, len(expected))
+	}
+	for key, value := range expected {
+		if fields[key] != value {
+			t.Errorf("field %q = %#v, expected %#v", key, fields[key], value)
+		}
+	}
+}
+
+func TestWarnAndFatalLevels(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	previous := log
+	// Panic instead of exiting so the Fatal entry can be inspected
+	log = zap.New(core, zap.WithFatalHook(zapcore.WriteThenPanic))
+	defer func() { log = previous }()
+
+	Warn("disk almost full")
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("Fatal did not invoke the fatal hook")
+			}
+		}()
+		Fatal("cannot start")
+	}()
+
+	entries := logs.All()
+	if len(entries) != 2 {
+		t.Fatalf("recorded %d entries, expected 2", len(entries))
+	}
+	if entries[0].Level != zapcore.WarnLevel {
+		t.Errorf("Warn recorded at %v", entries[0].Level)
+	}
+	if entries[1].Level != zapcore.FatalLevel {
+		t.Errorf("Fatal recorded at %v", entries[1].Level)
+	}
+}
+
+func TestWithContext(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	previous := log
+	log = zap.New(core)
+	defer func() { log = previous }()
+
+	ctx := ContextWithRequestID(context.Background(), "req-123")
+	WithContext(ctx).Info("handling order")
+	WithContext(context.Background()).Info("background job")
+
+	entries := logs.All()
+	if got := entries[0].ContextMap()["request_id"]; got != "req-123" {
+		t.Errorf("request_id = %v, expected req-123", got)
+	}
+	if _, ok := entries[1].ContextMap()["request_id"]; ok {
+		t.Error("request_id attached without one in the context")
+	}
+}
+
+func TestInitConsoleEncoding(t *testing.T) {
+	restoreLoggerState(t)
+
+	path := filepath.Join(t.TempDir(), "console.log")
+	if err := Init(Options{Encoding: "console", Level: "debug", OutputPaths: []string{path}}); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	Debug("console message", zap.String("order_id", "order-1"))
+	log.Sync()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	line := strings.TrimSpace(string(data))
+	if !strings.Contains(line, "console message") {
+		t.Fatalf("message missing from output %q", line)
+	}
+	if json.Valid([]byte(line)) {
+		t.Errorf("console encoding produced JSON: %s", line)
+	}
+}
+
+func TestInitReadsEnvironment(t *testing.T) {
+	restoreLoggerState(t)
+	t.Setenv("LOG_LEVEL", "warn")
+	t.Setenv("LOG_FORMAT", "json")
+
+	if err := Init(Options{OutputPaths: []string{filepath.Join(t.TempDir(), "env.log")}}); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	if GetLevel() != zapcore.WarnLevel {
+		t.Errorf("level = %v, expected warn from LOG_LEVEL", GetLevel())
+	}
+}
+
+func TestInitRejectsUnknownEncoding(t *testing.T) {
+	restoreLoggerState(t)
+	if err := Init(Options{Encoding: "xml"}); err == nil {
+		t.Error("Init accepted an unknown encoding")
+	}
+}
+
+func TestInitFileRotation(t *testing.T) {
+	restoreLoggerState(t)
+
+	dir := t.TempDir()
+	if err := Init(Options{File: filepath.Join(dir, "service.log"), MaxSize: 1, MaxBackups: 2}); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+
+	// Write well over MaxSize (1MB) to force at least one rotation; distinct messages
+	// keep the production sampler from dropping entries
+	payload := strings.Repeat("x", 512)
+	for i := 0; i < 4000; i++ {
+		Info(fmt.Sprintf("filling log file %d", i), zap.String("payload", payload))
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir failed: %v", err)
+	}
+	if len(entries) < 2 {
+		t.Errorf("found %d files in the log directory, expected a rotated backup", len(entries))
+	}
+}
+
+func TestWithFields(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	previous := log
+	log = zap.New(core)
+	defer func() { log = previous }()
+
+	scoped := WithFields(zap.String("module", "payments"))
+	scoped.Info("charge started")
+	scoped.Info("charge completed")
+	Info("unscoped")
+
+	entries := logs.All()
+	for _, entry := range entries[:2] {
+		if entry.ContextMap()["module"] != "payments" {
+			t.Errorf("%q missing module field", entry.Message)
+		}
+	}
+	if _, ok := entries[2].ContextMap()["module"]; ok {
+		t.Error("scoped fields leaked into the package logger")
+	}
+}
+
+func TestSetGlobalFields(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	previousLog, previousBase := log, base
+	base = zap.New(core)
+	defer func() {
+		log, base = previousLog, previousBase
+		globalFields = nil
+	}()
+
+	SetGlobalFields(zap.String("service", "order-service"))
+	SetGlobalFields(zap.String("service", "payment-service"))
+	Info("started")
+
+	fields := logs.All()[0].Context
+	if len(fields) != 1 || fields[0].String != "payment-service" {
+		t.Errorf("fields = %v, expected only service=payment-service", fields)
+	}
+}
+
+func TestInitSampling(t *testing.T) {
+	restoreLoggerState(t)
+
+	path := filepath.Join(t.TempDir(), "sampled.log")
+	err := Init(Options{OutputPaths: []string{path}, Sampling: &zap.SamplingConfig{Initial: 5, Thereafter: 50}})
+	if err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	for i := 0; i < 500; i++ {
+		Info("queue poll returned no work")
+	}
+	log.Sync()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	// 5 initial entries plus every 50th of the rest, i.e. 14 within one sampling tick
+	if lines := strings.Count(string(data), "\n"); lines < 5 || lines >= 100 {
+		t.Errorf("emitted %d of 500 identical entries, expected a sampled subset", lines)
+	}
+}
+
+func TestLevelHandler(t *testing.T) {
+	core, logs := observer.New(AtomicLevel())
+	previous := log
+	log = zap.New(core)
+	defer func() {
+		log = previous
+		SetLevel(zapcore.InfoLevel)
+	}()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	RegisterLevelEndpoint(router)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/loglevel", nil))
+	if !strings.Contains(w.Body.String(), `"level":"info"`) {
+		t.Errorf("GET /loglevel returned %s", w.Body.String())
+	}
+
+	Debug("before")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/loglevel", strings.NewReader(`{"level":"debug"}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("PUT /loglevel returned %d: %s", w.Code, w.Body.String())
+	}
+	Debug("after")
+
+	if logs.FilterMessage("before").Len() != 0 {
+		t.Error("Debug emitted before the level was changed")
+	}
+	if logs.FilterMessage("after").Len() != 1 {
+		t.Error("Debug not emitted after PUT /loglevel debug")
+	}
+}
+
+func TestNewDefaultLoggerFallback(t *testing.T) {
+	previous := buildConfig
+	buildConfig = func(zap.Config, ...zap.Option) (*zap.Logger, error) {
+		return nil, errors.New("cannot open sink")
+	}
+	defer func() { buildConfig = previous }()
+
+	fallback := newDefaultLogger()
+	if fallback == nil {
+		t.Fatal("newDefaultLogger returned nil when the build failed")
+	}
+	fallback.Info("still logging")
+}
+
+func TestSync(t *testing.T) {
+	Info("before sync")
+	if err := Sync(); err != nil {
+		t.Errorf("Sync returned %v", err)
+	}
+}
+
+func TestSetLoggerRestore(t *testing.T) {
+	restoreLoggerState(t)
+	SetGlobalFields(zap.String("service", "order-service"))
+	previous := GetLogger()
+
+	core, logs := observer.New(zapcore.InfoLevel)
+	restore := SetLogger(zap.New(core))
+	Info("replaced")
+	restore()
+
+	if GetLogger() != previous {
+		t.Error("restore did not reinstate the previous logger")
+	}
+	if fields := logs.All()[0].Context; len(fields) != 1 || fields[0].String != "order-service" {
+		t.Errorf("fields = %v, expected the global service field once", fields)
+	}
+}
+
+func TestLogError(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	t.Cleanup(SetLogger(zap.New(core)))
+
+	appErr := apperrors.NewAppError("ORDER_NOT_FOUND", "order does not exist", errors.New("no rows"))
+	LogError(fmt.Errorf("loading order: %w", appErr), "lookup failed")
+	LogError(errors.New("timeout"), "plain failure")
+
+	entries := logs.All()
+	fields := entries[0].ContextMap()
+	if fields["code"] != "ORDER_NOT_FOUND" {
+		t.Errorf("code = %v, expected ORDER_NOT_FOUND", fields["code"])
+	}
+	if fields["message"] != "order does not exist" {
+		t.Errorf("message = %v", fields["message"])
+	}
+	if fields["error"] != "no rows" {
+		t.Errorf("error = %v, expected the AppError cause", fields["error"])
+	}
+
+	plain := entries[1].ContextMap()
+	if _, ok := plain["code"]; ok {
+		t.Error("code field logged for a plain error")
+	}
+	if plain["error"] != "timeout" {
+		t.Errorf("error = %v, expected timeout", plain["error"])
+	}
+}
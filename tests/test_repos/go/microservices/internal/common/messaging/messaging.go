@@ -1,18 +1,53 @@
 package messaging
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/greenfuze/go-microservices/internal/common/config"
 	"github.com/greenfuze/go-microservices/internal/common/logger"
 	"github.com/nats-io/nats.go"
+	"go.uber.org/zap"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
 )
 
-var nc *nats.Conn
+// ErrNotConnected is returned when messaging is used before Connect
+var ErrNotConnected = errors.New("NATS not connected")
+
+// Default reconnect behavior applied by Connect and ConnectWithOptions
+var (
+	DefaultMaxReconnects = 60
+	DefaultReconnectWait = 2 * time.Second
+)
+
+// DefaultFlushTimeout bounds PublishWithContext when ctx carries no deadline
+var DefaultFlushTimeout = 2 * time.Second
+
+// ErrDrainTimeout is returned by Drain when the connection has not closed within its timeout
+var ErrDrainTimeout = errors.New("NATS drain timed out")
+
+var (
+	nc                 *nats.Conn
+	connClosed         chan struct{} // closed once nc has closed, see setConn
+	reconnectCallbacks []func(*nats.Conn)
+	callbacksMu        sync.Mutex
+)
 
 // Connect establishes a NATS connection
 func Connect() (*nats.Conn, error) {
+	return ConnectWithOptions()
+}
+
+// ConnectWithOptions establishes a NATS connection with extra options applied after the
+// package defaults for reconnects and connection-state logging, so they can override them
+func ConnectWithOptions(opts ...nats.Option) (*nats.Conn, error) {
 	cfg := config.GetConfig()
 	if cfg == nil {
-		return nil, logger.GetLogger().Sugar().Error("Config not loaded")
+		return nil, errors.New("config not loaded")
 	}
 
 	url := cfg.NATS.URL
@@ -20,40 +55,379 @@ func Connect() (*nats.Conn, error) {
 		url = nats.DefaultURL
 	}
 
-	var err error
-	nc, err = nats.Connect(url)
+	options := append(defaultOptions(), securityOptions(cfg.NATS)...)
+	conn, err := nats.Connect(url, append(options, opts...)...)
 	if err != nil {
 		return nil, err
 	}
+	setConn(conn)
 
 	logger.Info("NATS connection established")
 	return nc, nil
 }
 
+// setConn installs conn as the package connection, wrapping its ClosedHandler so that Drain
+// can wait for the close
+func setConn(conn *nats.Conn) {
+	closed := make(chan struct{})
+	var once sync.Once
+	previous := conn.ClosedHandler()
+	conn.SetClosedHandler(func(c *nats.Conn) {
+		once.Do(func() { close(closed) })
+		if previous != nil {
+			previous(c)
+		}
+	})
+	nc, connClosed = conn, closed
+}
+
+// OnReconnect registers fn to be called every time the connection is re-established
+func OnReconnect(fn func(*nats.Conn)) {
+	callbacksMu.Lock()
+	defer callbacksMu.Unlock()
+	reconnectCallbacks = append(reconnectCallbacks, fn)
+}
+
+func defaultOptions() []nats.Option {
+	return []nats.Option{
+		nats.MaxReconnects(DefaultMaxReconnects),
+		nats.ReconnectWait(DefaultReconnectWait),
+		nats.DisconnectErrHandler(func(conn *nats.Conn, err error) {
+			logger.Error("NATS disconnected", zap.Error(err))
+		}),
+		nats.ReconnectHandler(func(conn *nats.Conn) {
+			logger.Info("NATS reconnected", zap.String("url", conn.ConnectedUrl()))
+			callbacksMu.Lock()
+			callbacks := append([]func(*nats.Conn){}, reconnectCallbacks...)
+			callbacksMu.Unlock()
+			for _, fn := range callbacks {
+				fn(conn)
+			}
+		}),
+	}
+}
+
+// securityOptions builds the credentials and TLS options configured in cfg
+// A client certificate is only used when both TLSCert and TLSKey are set
+func securityOptions(cfg config.NATSConfig) []nats.Option {
+	var opts []nats.Option
+	if cfg.CredsFile != "" {
+		opts = append(opts, nats.UserCredentials(cfg.CredsFile))
+	}
+	if cfg.TLSCert != "" && cfg.TLSKey != "" {
+		opts = append(opts, nats.ClientCert(cfg.TLSCert, cfg.TLSKey))
+	}
+	if cfg.CAFile != "" {
+		opts = append(opts, nats.RootCAs(cfg.CAFile))
+	}
+	return opts
+}
+
 // GetConn returns the NATS connection
 func GetConn() *nats.Conn {
 	return nc
 }
 
 // Publish publishes a message to a subject
-func Publish(subject string, data []byte) error {
+func Publish(subject string, data []byte) (err error) {
+	defer func() { observePublish(subject, err) }()
 	if nc == nil {
 		logger.Error("NATS not connected")
-		return errors.New("NATS not connected")
+		return ErrNotConnected
 	}
 	return nc.Publish(subject, data)
 }
 
+// PublishObserver, when set, is called with the subject and result of every Publish,
+// PublishJSON and PublishWithContext call
+// The metrics package installs it to count publishes, so messaging needs no metrics import
+var PublishObserver func(subject string, err error)
+
+func observePublish(subject string, err error) {
+	if observer := PublishObserver; observer != nil {
+		observer(subject, err)
+	}
+}
+
+// PublishWithContext publishes a message and flushes it to the server, giving up when ctx is
+// canceled or its deadline passes
+func PublishWithContext(ctx context.Context, subject string, data []byte) (err error) {
+	defer func() { observePublish(subject, err) }()
+	if nc == nil {
+		logger.Error("NATS not connected")
+		return ErrNotConnected
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultFlushTimeout)
+		defer cancel()
+	}
+
+	if err := nc.Publish(subject, data); err != nil {
+		return err
+	}
+	if err := nc.FlushWithContext(ctx); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		return err
+	}
+	return nil
+}
+
 // Subscribe subscribes to a subject
 func Subscribe(subject string, handler nats.MsgHandler) (*nats.Subscription, error) {
 	if nc == nil {
 		logger.Error("NATS not connected")
-		return nil, errors.New("NATS not connected")
+		return nil, ErrNotConnected
+	}
+	sub, err := nc.Subscribe(subject, handler)
+	if err != nil {
+		return nil, err
+	}
+	track(sub)
+	return sub, nil
+}
+
+// SubscribeQueue subscribes to subject as a member of a queue group
+// Unlike Subscribe, where every subscriber receives every message, each message is
+// delivered to only one member of the group, balancing work across replicas
+func SubscribeQueue(subject, queue string, handler nats.MsgHandler) (*nats.Subscription, error) {
+	if nc == nil {
+		logger.Error("NATS not connected")
+		return nil, ErrNotConnected
+	}
+	sub, err := nc.QueueSubscribe(subject, queue, handler)
+	if err != nil {
+		return nil, err
+	}
+	track(sub)
+	return sub, nil
+}
+
+// SubscribeWildcard subscribes to a wildcard pattern and passes handler the subject tokens
+// matched by the pattern's wildcards, in order
+// In NATS subjects "*" matches exactly one dot-separated token and ">" matches one or more
+// trailing tokens, so it may only appear last; every token matched by ">" is passed separately
+// e.g. pattern "orders.*.created" on subject "orders.123.created" yields ["123"]
+func SubscribeWildcard(pattern string, handler func(tokens []string, data []byte)) (*nats.Subscription, error) {
+	return Subscribe(pattern, func(msg *nats.Msg) {
+		handler(wildcardTokens(pattern, msg.Subject), msg.Data)
+	})
+}
+
+func wildcardTokens(pattern, subject string) []string {
+	patternTokens := strings.Split(pattern, ".")
+	subjectTokens := strings.Split(subject, ".")
+
+	var tokens []string
+	for i, p := range patternTokens {
+		if i >= len(subjectTokens) {
+			break
+		}
+		switch p {
+		case "*":
+			tokens = append(tokens, subjectTokens[i])
+		case ">":
+			return append(tokens, subjectTokens[i:]...)
+		}
+	}
+	return tokens
+}
+
+// Headers set on messages forwarded to a dead-letter subject by SubscribeWithDLQ
+const (
+	HeaderDLQError           = "X-DLQ-Error"
+	HeaderDLQOriginalSubject = "X-DLQ-Original-Subject"
+)
+
+// SubscribeWithDLQ subscribes to a subject and republishes any message whose handler returns
+// an error or panics to dlqSubject, keeping the original payload and headers and adding
+// HeaderDLQError and HeaderDLQOriginalSubject to describe the failure
+func SubscribeWithDLQ(subject, dlqSubject string, handler func(*nats.Msg) error) (*nats.Subscription, error) {
+	return Subscribe(subject, func(msg *nats.Msg) {
+		err := runHandler(handler, msg)
+		if err == nil {
+			return
+		}
+		logger.Error("Message handler failed, forwarding to dead-letter subject",
+			zap.String("subject", msg.Subject), zap.String("dlq", dlqSubject), zap.Error(err))
+
+		dead := nats.NewMsg(dlqSubject)
+		dead.Data = msg.Data
+		for key, values := range msg.Header {
+			dead.Header[key] = append([]string(nil), values...)
+		}
+		dead.Header.Set(HeaderDLQError, err.Error())
+		dead.Header.Set(HeaderDLQOriginalSubject, msg.Subject)
+		if err := nc.PublishMsg(dead); err != nil {
+			logger.Error("Failed to publish to dead-letter subject", zap.String("dlq", dlqSubject), zap.Error(err))
+		}
+	})
+}
+
+func runHandler(handler func(*nats.Msg) error, msg *nats.Msg) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("handler panic: %v", r)
+		}
+	}()
+	return handler(msg)
+}
+
+// PublishJSON marshals v to JSON and publishes it to subject
+func PublishJSON(subject string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return Publish(subject, data)
+}
+
+// SubscribeJSON subscribes to subject and unmarshals each message into a fresh value of
+// prototype's type before calling handler
+// A pointer prototype yields pointers, a value prototype yields values; malformed payloads
+// are logged and skipped
+func SubscribeJSON(subject string, prototype interface{}, handler func(interface{})) (*nats.Subscription, error) {
+	if prototype == nil {
+		return nil, errors.New("SubscribeJSON requires a non-nil prototype")
+	}
+	typ := reflect.TypeOf(prototype)
+	isPtr := typ.Kind() == reflect.Ptr
+	if isPtr {
+		typ = typ.Elem()
+	}
+
+	return Subscribe(subject, func(msg *nats.Msg) {
+		value := reflect.New(typ)
+		if err := json.Unmarshal(msg.Data, value.Interface()); err != nil {
+			logger.Error("Failed to decode JSON message", zap.String("subject", msg.Subject), zap.Error(err))
+			return
+		}
+		if isPtr {
+			handler(value.Interface())
+		} else {
+			handler(value.Elem().Interface())
+		}
+	})
+}
+
+// Request sends data to subject and waits up to timeout for a single reply
+// A timeout is returned wrapping nats.ErrTimeout so callers can check it with errors.Is
+func Request(subject string, data []byte, timeout time.Duration) ([]byte, error) {
+	if nc == nil {
+		logger.Error("NATS not connected")
+		return nil, ErrNotConnected
+	}
+	msg, err := nc.Request(subject, data, timeout)
+	if errors.Is(err, nats.ErrTimeout) {
+		return nil, fmt.Errorf("request to %s timed out after %v: %w", subject, timeout, nats.ErrTimeout)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return msg.Data, nil
+}
+
+// RespondTo subscribes to subject and replies to each request with the handler's result
+// Messages without a reply subject are ignored
+func RespondTo(subject string, handler func([]byte) []byte) (*nats.Subscription, error) {
+	return Subscribe(subject, func(msg *nats.Msg) {
+		if msg.Reply == "" {
+			return
+		}
+		if err := msg.Respond(handler(msg.Data)); err != nil {
+			logger.Error("Failed to respond to request", zap.String("subject", subject), zap.Error(err))
+		}
+	})
+}
+
+// DurableAckWait is how long JetStream waits for an Ack before redelivering to SubscribeDurable
+var DurableAckWait = 30 * time.Second
+
+// EnsureStream creates the JetStream stream name capturing subjects, or updates its
+// subjects if it already exists
+func EnsureStream(name string, subjects []string) error {
+	js, err := jetStream()
+	if err != nil {
+		return err
+	}
+
+	info, err := js.StreamInfo(name)
+	if errors.Is(err, nats.ErrStreamNotFound) {
+		_, err = js.AddStream(&nats.StreamConfig{Name: name, Subjects: subjects})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	info.Config.Subjects = subjects
+	_, err = js.UpdateStream(&info.Config)
+	return err
+}
+
+// PublishPersistent publishes data to a subject captured by a JetStream stream and waits for
+// the server to acknowledge that it was stored
+func PublishPersistent(subject string, data []byte) (*nats.PubAck, error) {
+	js, err := jetStream()
+	if err != nil {
+		return nil, err
+	}
+	return js.Publish(subject, data)
+}
+
+// SubscribeDurable creates a durable JetStream consumer with explicit acknowledgement
+// The handler must call msg.Ack once the message is processed; messages that are not acked
+// within DurableAckWait are redelivered, including across restarts of the consumer
+func SubscribeDurable(subject, durable string, handler func(*nats.Msg)) (*nats.Subscription, error) {
+	js, err := jetStream()
+	if err != nil {
+		return nil, err
+	}
+	return js.Subscribe(subject, handler,
+		nats.Durable(durable),
+		nats.ManualAck(),
+		nats.AckExplicit(),
+		nats.AckWait(DurableAckWait),
+	)
+}
+
+func jetStream() (nats.JetStreamContext, error) {
+	if nc == nil {
+		logger.Error("NATS not connected")
+		return nil, ErrNotConnected
+	}
+	return nc.JetStream()
+}
+
+// Drain unsubscribes every subscription, lets handlers finish the messages already delivered,
+// flushes pending publishes and then closes the connection, blocking until it is closed
+// If that takes longer than timeout the connection is closed immediately and ErrDrainTimeout
+// is returned
+// Use it for graceful shutdown; Close is the hard stop that drops in-flight messages
+func Drain(timeout time.Duration) error {
+	if nc == nil {
+		return ErrNotConnected
+	}
+	if err := nc.Drain(); err != nil {
+		return err
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-connClosed:
+		return nil
+	case <-timer.C:
+		nc.Close()
+		return ErrDrainTimeout
 	}
-	return nc.Subscribe(subject, handler)
 }
 
-// Close closes the NATS connection
+// Close closes the NATS connection immediately, dropping messages still being processed
 func Close() {
 	if nc != nil {
 		nc.Close()
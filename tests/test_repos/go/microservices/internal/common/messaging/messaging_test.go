@@ -1,9 +1,508 @@
 package messaging
 
-import "testing"
+import (
+	"context"
+	"errors"
+	"github.com/greenfuze/go-microservices/internal/common/config"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// startTestServer runs an embedded NATS server and connects the package to it for the duration of the test
+func startTestServer(t *testing.T) *server.Server {
+	t.Helper()
+	return runTestServer(t, &server.Options{Host: "127.0.0.1", Port: -1, NoLog: true, NoSigs: true})
+}
+
+// startJetStreamServer is like startTestServer with JetStream enabled
+func startJetStreamServer(t *testing.T) *server.Server {
+	t.Helper()
+	return runTestServer(t, &server.Options{
+		Host:      "127.0.0.1",
+		Port:      -1,
+		NoLog:     true,
+		NoSigs:    true,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+	})
+}
+
+func runTestServer(t *testing.T, opts *server.Options) *server.Server {
+	t.Helper()
+	srv, err := server.NewServer(opts)
+	if err != nil {
+		t.Fatalf("NewServer failed: %v", err)
+	}
+	go srv.Start()
+	if !srv.ReadyForConnections(5 * time.Second) {
+		t.Fatal("NATS server not ready")
+	}
+
+	conn, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("nats.Connect failed: %v", err)
+	}
+	setConn(conn)
+	t.Cleanup(func() {
+		Close()
+		nc = nil
+		srv.Shutdown()
+	})
+	return srv
+}
 
 func TestGetConn(t *testing.T) {
 	conn := GetConn()
 	// In test environment, conn might be nil if Connect wasn't called
 	_ = conn
 }
+
+func TestPublishNotConnected(t *testing.T) {
+	if err := Publish("orders.created", []byte("{}")); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("Publish returned %v, expected ErrNotConnected", err)
+	}
+	if _, err := Subscribe("orders.created", nil); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("Subscribe returned %v, expected ErrNotConnected", err)
+	}
+}
+
+func TestRequestReply(t *testing.T) {
+	startTestServer(t)
+
+	if _, err := RespondTo("users.lookup", func(data []byte) []byte {
+		return []byte(strings.ToUpper(string(data)))
+	}); err != nil {
+		t.Fatalf("RespondTo failed: %v", err)
+	}
+
+	reply, err := Request("users.lookup", []byte("alice"), time.Second)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	if string(reply) != "ALICE" {
+		t.Errorf("Request returned %q, expected ALICE", reply)
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	startTestServer(t)
+
+	// A subscriber that never replies keeps the server from answering with no-responders
+	if _, err := Subscribe("slow.service", func(*nats.Msg) {}); err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	if _, err := Request("slow.service", nil, 50*time.Millisecond); !errors.Is(err, nats.ErrTimeout) {
+		t.Errorf("Request returned %v, expected nats.ErrTimeout", err)
+	}
+}
+
+func TestRequestNotConnected(t *testing.T) {
+	if _, err := Request("users.lookup", nil, time.Second); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("Request returned %v, expected ErrNotConnected", err)
+	}
+}
+
+type orderEvent struct {
+	OrderID string  `json:"order_id"`
+	Amount  float64 `json:"amount"`
+}
+
+func TestPublishSubscribeJSON(t *testing.T) {
+	startTestServer(t)
+
+	received := make(chan interface{}, 2)
+	if _, err := SubscribeJSON("orders.created", orderEvent{}, func(v interface{}) { received <- v }); err != nil {
+		t.Fatalf("SubscribeJSON failed: %v", err)
+	}
+
+	if err := Publish("orders.created", []byte("not json")); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	if err := PublishJSON("orders.created", orderEvent{OrderID: "123", Amount: 99.5}); err != nil {
+		t.Fatalf("PublishJSON failed: %v", err)
+	}
+
+	select {
+	case v := <-received:
+		event, ok := v.(orderEvent)
+		if !ok {
+			t.Fatalf("handler received %T, expected orderEvent", v)
+		}
+		if event.OrderID != "123" || event.Amount != 99.5 {
+			t.Errorf("handler received %+v", event)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for the JSON message")
+	}
+}
+
+func TestSubscribeQueueDeliversOnce(t *testing.T) {
+	startTestServer(t)
+
+	var deliveries atomic.Int32
+	handler := func(*nats.Msg) { deliveries.Add(1) }
+	for i := 0; i < 2; i++ {
+		if _, err := SubscribeQueue("notifications.send", "notification-service", handler); err != nil {
+			t.Fatalf("SubscribeQueue failed: %v", err)
+		}
+	}
+
+	if err := Publish("notifications.send", []byte("hello")); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	if err := GetConn().Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	if n := deliveries.Load(); n != 1 {
+		t.Errorf("message delivered %d times across the queue group, expected 1", n)
+	}
+}
+
+func TestConnectWithOptionsWiresReconnectHandlers(t *testing.T) {
+	srv := startTestServer(t)
+	Close()
+
+	if _, err := config.LoadConfig(); err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	config.GetConfig().NATS.URL = srv.ClientURL()
+
+	conn, err := ConnectWithOptions(nats.Name("messaging-test"))
+	if err != nil {
+		t.Fatalf("ConnectWithOptions failed: %v", err)
+	}
+
+	if conn.Opts.MaxReconnect != DefaultMaxReconnects || conn.Opts.ReconnectWait != DefaultReconnectWait {
+		t.Errorf("reconnect defaults not applied: %d, %v", conn.Opts.MaxReconnect, conn.Opts.ReconnectWait)
+	}
+	if conn.Opts.DisconnectedErrCB == nil || conn.Opts.ReconnectedCB == nil {
+		t.Fatal("disconnect/reconnect handlers not wired")
+	}
+	if conn.Opts.Name != "messaging-test" {
+		t.Errorf("caller option not applied, Name = %q", conn.Opts.Name)
+	}
+
+	called := make(chan struct{}, 1)
+	OnReconnect(func(*nats.Conn) { called <- struct{}{} })
+	defer func() { reconnectCallbacks = nil }()
+
+	conn.Opts.ReconnectedCB(conn)
+	select {
+	case <-called:
+	default:
+		t.Error("registered reconnect callback was not invoked")
+	}
+}
+
+func TestSecurityOptions(t *testing.T) {
+	if opts := securityOptions(config.NATSConfig{URL: nats.DefaultURL}); len(opts) != 0 {
+		t.Errorf("got %d options without credentials or TLS configured, expected none", len(opts))
+	}
+
+	creds := filepath.Join(t.TempDir(), "service.creds")
+	if err := os.WriteFile(creds, nil, 0o600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	opts := securityOptions(config.NATSConfig{CredsFile: creds})
+	if len(opts) != 1 {
+		t.Fatalf("got %d options, expected 1", len(opts))
+	}
+	var applied nats.Options
+	for _, opt := range opts {
+		if err := opt(&applied); err != nil {
+			t.Fatalf("applying option failed: %v", err)
+		}
+	}
+	// nats.UserCredentials installs the JWT and signature callbacks that load the creds file
+	if applied.UserJWT == nil || applied.SignatureCB == nil {
+		t.Error("nats.UserCredentials not included in options")
+	}
+}
+
+func TestSubscribeDurableRedeliversUnacked(t *testing.T) {
+	startJetStreamServer(t)
+
+	previous := DurableAckWait
+	DurableAckWait = 200 * time.Millisecond
+	defer func() { DurableAckWait = previous }()
+
+	if err := EnsureStream("ORDERS", []string{"orders.>"}); err != nil {
+		t.Fatalf("EnsureStream failed: %v", err)
+	}
+	if err := EnsureStream("ORDERS", []string{"orders.>"}); err != nil {
+		t.Fatalf("EnsureStream on an existing stream failed: %v", err)
+	}
+
+	deliveries := make(chan *nats.Msg, 4)
+	var attempts atomic.Int32
+	_, err := SubscribeDurable("orders.created", "order-processor", func(msg *nats.Msg) {
+		// Leave the first delivery unacked so JetStream redelivers it
+		if attempts.Add(1) > 1 {
+			msg.Ack()
+		}
+		deliveries <- msg
+	})
+	if err != nil {
+		t.Fatalf("SubscribeDurable failed: %v", err)
+	}
+
+	ack, err := PublishPersistent("orders.created", []byte("order-1"))
+	if err != nil {
+		t.Fatalf("PublishPersistent failed: %v", err)
+	}
+	if ack.Stream != "ORDERS" {
+		t.Errorf("message stored in stream %q, expected ORDERS", ack.Stream)
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case msg := <-deliveries:
+			if string(msg.Data) != "order-1" {
+				t.Errorf("delivery %d carried %q", i+1, msg.Data)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for delivery %d", i+1)
+		}
+	}
+}
+
+func TestJetStreamNotConnected(t *testing.T) {
+	if err := EnsureStream("ORDERS", []string{"orders.>"}); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("EnsureStream returned %v, expected ErrNotConnected", err)
+	}
+	if _, err := PublishPersistent("orders.created", nil); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("PublishPersistent returned %v, expected ErrNotConnected", err)
+	}
+}
+
+func TestDrainWaitsForSlowHandler(t *testing.T) {
+	startTestServer(t)
+
+	started := make(chan struct{})
+	var finished atomic.Bool
+	_, err := Subscribe("orders.slow", func(msg *nats.Msg) {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		finished.Store(true)
+	})
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	if err := Publish("orders.slow", []byte("order-1")); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+
+	select {
+	case <-started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("handler was not invoked")
+	}
+
+	if err := Drain(2 * time.Second); err != nil {
+		t.Fatalf("Drain failed: %v", err)
+	}
+	if !finished.Load() {
+		t.Error("Drain returned before the handler finished")
+	}
+	if !nc.IsClosed() {
+		t.Error("connection still open after Drain")
+	}
+}
+
+func TestDrainTimesOut(t *testing.T) {
+	startTestServer(t)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	if _, err := Subscribe("orders.stuck", func(msg *nats.Msg) {
+		close(started)
+		<-release
+	}); err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	if err := Publish("orders.stuck", []byte("order-1")); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	select {
+	case <-started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("handler was not invoked")
+	}
+
+	start := time.Now()
+	if err := Drain(100 * time.Millisecond); !errors.Is(err, ErrDrainTimeout) {
+		t.Errorf("Drain returned %v, expected ErrDrainTimeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Drain took %v despite a 100ms timeout", elapsed)
+	}
+	if !nc.IsClosed() {
+		t.Error("connection still open after Drain timed out")
+	}
+}
+
+func TestDrainNotConnected(t *testing.T) {
+	if err := Drain(time.Second); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("Drain returned %v, expected ErrNotConnected", err)
+	}
+}
+
+func TestPublishWithContext(t *testing.T) {
+	startTestServer(t)
+
+	received := make(chan []byte, 1)
+	if _, err := Subscribe("orders.created", func(msg *nats.Msg) { received <- msg.Data }); err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+
+	if err := PublishWithContext(context.Background(), "orders.created", []byte("order-1")); err != nil {
+		t.Fatalf("PublishWithContext failed: %v", err)
+	}
+	select {
+	case data := <-received:
+		if string(data) != "order-1" {
+			t.Errorf("received %q", data)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for message")
+	}
+}
+
+func TestPublishWithContextCanceled(t *testing.T) {
+	startTestServer(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := PublishWithContext(ctx, "orders.created", []byte("order-1")); !errors.Is(err, context.Canceled) {
+		t.Errorf("PublishWithContext returned %v, expected context.Canceled", err)
+	}
+}
+
+func TestSubscribeWildcard(t *testing.T) {
+	startTestServer(t)
+
+	type delivery struct {
+		tokens []string
+		data   string
+	}
+	received := make(chan delivery, 1)
+	_, err := SubscribeWildcard("orders.*.created", func(tokens []string, data []byte) {
+		received <- delivery{tokens, string(data)}
+	})
+	if err != nil {
+		t.Fatalf("SubscribeWildcard failed: %v", err)
+	}
+	if err := Publish("orders.123.created", []byte("order-1")); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+
+	select {
+	case d := <-received:
+		if !reflect.DeepEqual(d.tokens, []string{"123"}) {
+			t.Errorf("tokens = %q, expected [123]", d.tokens)
+		}
+		if d.data != "order-1" {
+			t.Errorf("data = %q", d.data)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for message")
+	}
+}
+
+func TestWildcardTokens(t *testing.T) {
+	tests := []struct {
+		pattern, subject string
+		expected         []string
+	}{
+		{"orders.*.created", "orders.123.created", []string{"123"}},
+		{"orders.*.*", "orders.123.paid", []string{"123", "paid"}},
+		{"orders.>", "orders.eu.123.created", []string{"eu", "123", "created"}},
+		{"orders.*.>", "orders.123.items.7", []string{"123", "items", "7"}},
+		{"orders.created", "orders.created", nil},
+	}
+	for _, tt := range tests {
+		if got := wildcardTokens(tt.pattern, tt.subject); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("wildcardTokens(%q, %q) = %q, expected %q", tt.pattern, tt.subject, got, tt.expected)
+		}
+	}
+}
+
+func TestSubscribeWithDLQ(t *testing.T) {
+	startTestServer(t)
+
+	dead := make(chan *nats.Msg, 2)
+	if _, err := Subscribe("orders.dlq", func(msg *nats.Msg) { dead <- msg }); err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	_, err := SubscribeWithDLQ("orders.created", "orders.dlq", func(msg *nats.Msg) error {
+		if string(msg.Data) == "panic" {
+			panic("boom")
+		}
+		if string(msg.Data) == "bad" {
+			return errors.New("invalid order")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("SubscribeWithDLQ failed: %v", err)
+	}
+
+	for _, payload := range []string{"ok", "bad", "panic"} {
+		if err := Publish("orders.created", []byte(payload)); err != nil {
+			t.Fatalf("Publish failed: %v", err)
+		}
+	}
+
+	for _, expected := range []struct{ data, errText string }{{"bad", "invalid order"}, {"panic", "handler panic: boom"}} {
+		select {
+		case msg := <-dead:
+			if string(msg.Data) != expected.data {
+				t.Errorf("dead-lettered payload %q, expected %q", msg.Data, expected.data)
+			}
+			if got := msg.Header.Get(HeaderDLQError); got != expected.errText {
+				t.Errorf("%s = %q, expected %q", HeaderDLQError, got, expected.errText)
+			}
+			if got := msg.Header.Get(HeaderDLQOriginalSubject); got != "orders.created" {
+				t.Errorf("%s = %q, expected orders.created", HeaderDLQOriginalSubject, got)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for dead-lettered %q", expected.data)
+		}
+	}
+
+	select {
+	case msg := <-dead:
+		t.Errorf("unexpected dead-lettered message %q", msg.Data)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestPublishObserver(t *testing.T) {
+	startTestServer(t)
+
+	type result struct {
+		subject string
+		err     error
+	}
+	var results []result
+	PublishObserver = func(subject string, err error) { results = append(results, result{subject, err}) }
+	defer func() { PublishObserver = nil }()
+
+	if err := Publish("orders.created", []byte("order-1")); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	if len(results) != 1 || results[0].subject != "orders.created" || results[0].err != nil {
+		t.Errorf("observer saw %v, expected one successful publish to orders.created", results)
+	}
+}
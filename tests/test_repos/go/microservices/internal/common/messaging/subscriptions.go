@@ -0,0 +1,105 @@
+package messaging
+
+import (
+	"errors"
+	"github.com/nats-io/nats.go"
+	"sync"
+)
+
+// SubscriptionManager tracks subscriptions so they can be released together on shutdown
+type SubscriptionManager struct {
+	mu   sync.Mutex
+	subs []*nats.Subscription
+}
+
+var defaultManager *SubscriptionManager
+
+// NewSubscriptionManager creates an empty SubscriptionManager
+func NewSubscriptionManager() *SubscriptionManager {
+	return &SubscriptionManager{}
+}
+
+// SetSubscriptionManager registers m as the service-wide manager; every subscription made
+// with the package-level Subscribe and SubscribeQueue is then tracked by it
+// Pass nil to stop tracking
+func SetSubscriptionManager(m *SubscriptionManager) {
+	defaultManager = m
+}
+
+// Subscribe subscribes to a subject and tracks the subscription
+func (m *SubscriptionManager) Subscribe(subject string, handler nats.MsgHandler) (*nats.Subscription, error) {
+	sub, err := Subscribe(subject, handler)
+	if err != nil {
+		return nil, err
+	}
+	m.Track(sub)
+	return sub, nil
+}
+
+// SubscribeQueue subscribes to subject as a member of a queue group and tracks the subscription
+func (m *SubscriptionManager) SubscribeQueue(subject, queue string, handler nats.MsgHandler) (*nats.Subscription, error) {
+	sub, err := SubscribeQueue(subject, queue, handler)
+	if err != nil {
+		return nil, err
+	}
+	m.Track(sub)
+	return sub, nil
+}
+
+// Track adds a subscription created elsewhere to the manager
+func (m *SubscriptionManager) Track(sub *nats.Subscription) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, s := range m.subs {
+		if s == sub {
+			return
+		}
+	}
+	m.subs = append(m.subs, sub)
+}
+
+// Len returns the number of tracked subscriptions
+func (m *SubscriptionManager) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.subs)
+}
+
+// UnsubscribeAll unsubscribes every tracked subscription and stops tracking them
+// Messages already buffered for a subscription are discarded; use DrainAll on graceful shutdown
+// Subscriptions that are already closed are skipped
+func (m *SubscriptionManager) UnsubscribeAll() error {
+	return m.release((*nats.Subscription).Unsubscribe)
+}
+
+// DrainAll drains every tracked subscription and stops tracking them: no new messages are
+// delivered, while the ones already buffered are still handled
+// Draining completes in the background; messaging.Drain waits for it before closing the connection
+func (m *SubscriptionManager) DrainAll() error {
+	return m.release((*nats.Subscription).Drain)
+}
+
+// release applies fn to every tracked subscription that is still valid and clears the manager
+func (m *SubscriptionManager) release(fn func(*nats.Subscription) error) error {
+	m.mu.Lock()
+	subs := m.subs
+	m.subs = nil
+	m.mu.Unlock()
+
+	var errs []error
+	for _, sub := range subs {
+		if !sub.IsValid() {
+			continue
+		}
+		if err := fn(sub); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func track(sub *nats.Subscription) {
+	if m := defaultManager; m != nil {
+		m.Track(sub)
+	}
+}
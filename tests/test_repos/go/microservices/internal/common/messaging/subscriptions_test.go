@@ -0,0 +1,102 @@
+package messaging
+
+import (
+	"github.com/nats-io/nats.go"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSubscriptionManagerUnsubscribeAll(t *testing.T) {
+	startTestServer(t)
+
+	m := NewSubscriptionManager()
+	handler := func(msg *nats.Msg) {}
+
+	first, err := m.Subscribe("orders.created", handler)
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	second, err := m.SubscribeQueue("orders.created", "workers", handler)
+	if err != nil {
+		t.Fatalf("SubscribeQueue failed: %v", err)
+	}
+	if m.Len() != 2 {
+		t.Fatalf("tracking %d subscriptions, expected 2", m.Len())
+	}
+
+	if err := m.UnsubscribeAll(); err != nil {
+		t.Fatalf("UnsubscribeAll failed: %v", err)
+	}
+	if first.IsValid() || second.IsValid() {
+		t.Error("subscriptions still active after UnsubscribeAll")
+	}
+	if m.Len() != 0 {
+		t.Errorf("tracking %d subscriptions after UnsubscribeAll, expected 0", m.Len())
+	}
+}
+
+func TestSubscriptionManagerDrainAllKeepsBufferedMessages(t *testing.T) {
+	startTestServer(t)
+
+	m := NewSubscriptionManager()
+	var handled atomic.Int32
+	sub, err := m.Subscribe("orders.created", func(msg *nats.Msg) {
+		time.Sleep(50 * time.Millisecond)
+		handled.Add(1)
+	})
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := Publish("orders.created", []byte("order")); err != nil {
+			t.Fatalf("Publish failed: %v", err)
+		}
+	}
+	if err := GetConn().Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	if err := m.DrainAll(); err != nil {
+		t.Fatalf("DrainAll failed: %v", err)
+	}
+	if m.Len() != 0 {
+		t.Errorf("tracking %d subscriptions after DrainAll, expected 0", m.Len())
+	}
+	if err := Drain(2 * time.Second); err != nil {
+		t.Fatalf("Drain failed: %v", err)
+	}
+	if got := handled.Load(); got != 3 {
+		t.Errorf("handled %d of 3 buffered messages, expected all of them", got)
+	}
+	if sub.IsValid() {
+		t.Error("subscription still active after DrainAll")
+	}
+}
+
+func TestSetSubscriptionManagerTracksPackageSubscriptions(t *testing.T) {
+	startTestServer(t)
+
+	m := NewSubscriptionManager()
+	SetSubscriptionManager(m)
+	defer SetSubscriptionManager(nil)
+
+	sub, err := Subscribe("orders.created", func(msg *nats.Msg) {})
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	// Subscribing through the registered manager must not track the subscription twice
+	if _, err := m.Subscribe("orders.paid", func(msg *nats.Msg) {}); err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	if m.Len() != 2 {
+		t.Fatalf("tracking %d subscriptions, expected 2", m.Len())
+	}
+
+	if err := m.UnsubscribeAll(); err != nil {
+		t.Fatalf("UnsubscribeAll failed: %v", err)
+	}
+	if sub.IsValid() {
+		t.Error("package-level subscription still active after UnsubscribeAll")
+	}
+}
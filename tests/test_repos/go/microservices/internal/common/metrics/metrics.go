@@ -1,14 +1,33 @@
 package metrics
 
 import (
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/greenfuze/go-microservices/internal/common/logger"
 	"github.com/greenfuze/go-microservices/internal/common/messaging"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	// registry holds every collector in this package and is what Handler serves
+	// A private registry, unlike prometheus.DefaultRegisterer, lets collectors be replaced
+	// without duplicate-registration panics
+	registry                         = prometheus.NewRegistry()
+	registerer prometheus.Registerer = registry
+	registryMu sync.Mutex
 )
 
 var (
 	// RequestCounter counts HTTP requests
-	RequestCounter = promauto.NewCounterVec(
+	RequestCounter = promauto.With(registry).NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "http_requests_total",
 			Help: "Total number of HTTP requests",
@@ -17,15 +36,221 @@ var (
 	)
 
 	// RequestDuration tracks request duration
-	RequestDuration = promauto.NewHistogramVec(
+	RequestDuration = promauto.With(registry).NewHistogramVec(requestDurationOpts(nil), []string{"method", "endpoint"})
+
+	// InFlightRequests tracks requests currently being served
+	InFlightRequests = promauto.With(registry).NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "http_requests_in_flight",
+			Help: "Number of HTTP requests currently being served",
+		},
+		[]string{"endpoint"},
+	)
+
+	// ResponseStatus counts HTTP responses by status class
+	ResponseStatus = promauto.With(registry).NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "http_responses_total",
+			Help: "Total number of HTTP responses by status class",
+		},
+		[]string{"method", "endpoint", "status_class"},
+	)
+
+	// DBQueryDuration tracks database call duration by operation
+	DBQueryDuration = promauto.With(registry).NewHistogramVec(
 		prometheus.HistogramOpts{
-			Name: "http_request_duration_seconds",
-			Help: "HTTP request duration in seconds",
+			Name: "db_query_duration_seconds",
+			Help: "Database query duration in seconds",
 		},
-		[]string{"method", "endpoint"},
+		[]string{"operation"},
+	)
+
+	// MessagesPublished counts messages published to NATS
+	MessagesPublished = promauto.With(registry).NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nats_messages_published_total",
+			Help: "Total number of messages published to NATS",
+		},
+		[]string{"subject"},
+	)
+
+	// MessagePublishErrors counts failed NATS publishes
+	MessagePublishErrors = promauto.With(registry).NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "nats_message_publish_errors_total",
+			Help: "Total number of failed NATS publishes",
+		},
+		[]string{"subject"},
 	)
 )
 
+func init() {
+	messaging.PublishObserver = RecordPublish
+}
+
+// RecordPublish records the outcome of publishing a message to subject
+func RecordPublish(subject string, err error) {
+	if err != nil {
+		MessagePublishErrors.WithLabelValues(subject).Inc()
+		return
+	}
+	MessagesPublished.WithLabelValues(subject).Inc()
+}
+
+func requestDurationOpts(buckets []float64) prometheus.HistogramOpts {
+	return prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request duration in seconds",
+		Buckets: buckets,
+	}
+}
+
+// custom holds collectors added through RegisterCounter and RegisterHistogram
+var custom []prometheus.Collector
+
+// allCollectors returns the package's built-in and custom collectors
+func allCollectors() []prometheus.Collector {
+	builtin := []prometheus.Collector{RequestCounter, RequestDuration, InFlightRequests, ResponseStatus, DBQueryDuration,
+		MessagesPublished, MessagePublishErrors}
+	if buildInfo != nil {
+		builtin = append(builtin, buildInfo)
+	}
+	builtin = append(builtin, runtimeCollectors...)
+	return append(builtin, custom...)
+}
+
+// ResetMetrics clears every series of the package's metric vectors, including those from
+// RegisterCounter and RegisterHistogram
+// It is intended for test isolation, since vectors otherwise accumulate across tests
+func ResetMetrics() {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	RequestCounter.Reset()
+	RequestDuration.Reset()
+	InFlightRequests.Reset()
+	ResponseStatus.Reset()
+	DBQueryDuration.Reset()
+	MessagesPublished.Reset()
+	MessagePublishErrors.Reset()
+	for _, c := range custom {
+		if vec, ok := c.(interface{ Reset() }); ok {
+			vec.Reset()
+		}
+	}
+}
+
+// runtimeCollectors holds the Go and process collectors once EnableRuntimeMetrics has run
+var runtimeCollectors []prometheus.Collector
+
+// EnableRuntimeMetrics exposes Go runtime metrics (goroutines, GC, memory) and process
+// metrics (CPU, open fds, RSS) alongside the service's own; calling it again has no effect
+func EnableRuntimeMetrics() {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if runtimeCollectors != nil {
+		return
+	}
+	runtime := []prometheus.Collector{
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	}
+	for _, c := range runtime {
+		if err := registerer.Register(c); err != nil {
+			logger.Error("Failed to register runtime metric collector", zap.Error(err))
+		}
+	}
+	runtimeCollectors = runtime
+}
+
+// buildInfo is the gauge registered by RecordBuildInfo
+var buildInfo prometheus.Gauge
+
+// RecordBuildInfo exposes a build_info gauge set to 1 with version, commit and date as labels
+// Services call it at startup with values injected through -ldflags; calling it again
+// replaces the previous labels
+func RecordBuildInfo(version, commit, date string) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name:        "build_info",
+		Help:        "Build information of the running binary",
+		ConstLabels: prometheus.Labels{"version": version, "commit": commit, "date": date},
+	})
+	gauge.Set(1)
+	if buildInfo != nil {
+		registerer.Unregister(buildInfo)
+	}
+	if err := registerer.Register(gauge); err != nil {
+		logger.Error("Failed to register build info metric", zap.Error(err))
+		return
+	}
+	buildInfo = gauge
+}
+
+// RegisterCounter registers a business counter on the shared registry
+// Registering the same name and labels again returns the existing counter; it panics if the
+// name is taken by an incompatible collector, as promauto does
+func RegisterCounter(name, help string, labels []string) *prometheus.CounterVec {
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{Name: name, Help: help}, labels)
+	return registerCustom(counter).(*prometheus.CounterVec)
+}
+
+// RegisterHistogram registers a business histogram on the shared registry
+// Nil buckets use prometheus.DefBuckets; re-registration behaves as in RegisterCounter
+func RegisterHistogram(name, help string, labels []string, buckets []float64) *prometheus.HistogramVec {
+	histogram := prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: name, Help: help, Buckets: buckets}, labels)
+	return registerCustom(histogram).(*prometheus.HistogramVec)
+}
+
+func registerCustom(c prometheus.Collector) prometheus.Collector {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if err := registerer.Register(c); err != nil {
+		var existing prometheus.AlreadyRegisteredError
+		if errors.As(err, &existing) {
+			return existing.ExistingCollector
+		}
+		panic(err)
+	}
+	custom = append(custom, c)
+	return c
+}
+
+// SetServiceName adds a constant "service" label with name to every metric, so services
+// scraped into one Prometheus can be told apart; call it once at startup
+func SetServiceName(name string) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	fresh := prometheus.NewRegistry()
+	wrapped := prometheus.WrapRegistererWith(prometheus.Labels{"service": name}, fresh)
+	for _, c := range allCollectors() {
+		if err := wrapped.Register(c); err != nil {
+			logger.Error("Failed to register metric collector", zap.Error(err))
+		}
+	}
+	registry, registerer = fresh, wrapped
+}
+
+// InitMetrics replaces RequestDuration with a histogram using buckets (in seconds)
+// Nil buckets restore prometheus.DefBuckets; it is safe to call more than once
+func InitMetrics(buckets []float64) error {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	duration := prometheus.NewHistogramVec(requestDurationOpts(buckets), []string{"method", "endpoint"})
+	registerer.Unregister(RequestDuration)
+	if err := registerer.Register(duration); err != nil {
+		return err
+	}
+	RequestDuration = duration
+	return nil
+}
+
 // RecordRequest records a request metric
 func RecordRequest(method, endpoint string) {
 	RequestCounter.WithLabelValues(method, endpoint).Inc()
@@ -36,12 +261,72 @@ func RecordDuration(method, endpoint string, duration float64) {
 	RequestDuration.WithLabelValues(method, endpoint).Observe(duration)
 }
 
-// PublishMetrics publishes metrics via messaging
+// RecordDBQuery records the duration of a database call, e.g. operation "select" or "insert"
+func RecordDBQuery(operation string, duration float64) {
+	DBQueryDuration.WithLabelValues(operation).Observe(duration)
+}
+
+// RecordStatus records a response under its status class, e.g. 503 under "5xx"
+func RecordStatus(method, endpoint string, status int) {
+	ResponseStatus.WithLabelValues(method, endpoint, statusClass(status)).Inc()
+}
+
+func statusClass(status int) string {
+	return strconv.Itoa(status/100) + "xx"
+}
+
+// unmatchedRoute is the endpoint label for requests that match no registered route
+const unmatchedRoute = "unmatched"
+
+// MetricsMiddleware records RequestCounter, RequestDuration and ResponseStatus for every request and tracks
+// InFlightRequests while it is served
+// The endpoint label is the route template (e.g. /orders/:id) rather than the raw path,
+// keeping label cardinality bounded
+func MetricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		endpoint := c.FullPath()
+		if endpoint == "" {
+			endpoint = unmatchedRoute
+		}
+
+		inFlight := InFlightRequests.WithLabelValues(endpoint)
+		inFlight.Inc()
+		// Deferred before c.Next so the gauge drops even if a handler panics
+		defer inFlight.Dec()
+
+		c.Next()
+
+		RecordRequest(c.Request.Method, endpoint)
+		RecordDuration(c.Request.Method, endpoint, time.Since(start).Seconds())
+		RecordStatus(c.Request.Method, endpoint, c.Writer.Status())
+	}
+}
+
+// Handler serves the registered collectors in the Prometheus exposition format
+func Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		registryMu.Lock()
+		gatherer := registry
+		registryMu.Unlock()
+		promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{}).ServeHTTP(c.Writer, c.Request)
+	}
+}
+
+// RegisterMetricsEndpoint mounts Handler at GET /metrics for Prometheus to scrape
+func RegisterMetricsEndpoint(router *gin.Engine) {
+	router.GET("/metrics", Handler())
+}
+
+// PublishSubject is the NATS subject PublishMetrics publishes to
+var PublishSubject = "metrics"
+
+// PublishMetrics publishes metrics as a JSON object to PublishSubject via messaging
+// It returns messaging.ErrNotConnected when NATS is not connected
 func PublishMetrics(metrics map[string]float64) error {
-	conn := messaging.GetConn()
-	if conn == nil {
-		return nil
+	data, err := json.Marshal(metrics)
+	if err != nil {
+		return err
 	}
-	// In real implementation, serialize and publish metrics
-	return nil
+	return messaging.Publish(PublishSubject, data)
 }
@@ -1,6 +1,43 @@
 package metrics
 
-import "testing"
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/greenfuze/go-microservices/internal/common/config"
+	"github.com/greenfuze/go-microservices/internal/common/messaging"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// connectTestNATS runs an embedded NATS server and connects messaging to it for the duration of the test
+func connectTestNATS(t *testing.T) {
+	t.Helper()
+	srv, err := server.NewServer(&server.Options{Host: "127.0.0.1", Port: -1, NoLog: true, NoSigs: true})
+	if err != nil {
+		t.Fatalf("NewServer failed: %v", err)
+	}
+	go srv.Start()
+	if !srv.ReadyForConnections(5 * time.Second) {
+		t.Fatal("NATS server not ready")
+	}
+	t.Cleanup(srv.Shutdown)
+
+	if _, err := config.LoadConfig(); err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	config.GetConfig().NATS.URL = srv.ClientURL()
+	if _, err := messaging.Connect(); err != nil {
+		t.Fatalf("messaging.Connect failed: %v", err)
+	}
+	t.Cleanup(messaging.Close)
+}
 
 func TestRecordRequest(t *testing.T) {
 	RecordRequest("GET", "/test")
@@ -9,3 +46,331 @@ func TestRecordRequest(t *testing.T) {
 func TestRecordDuration(t *testing.T) {
 	RecordDuration("GET", "/test", 0.1)
 }
+
+func TestRecordDBQuery(t *testing.T) {
+	RecordDBQuery("select", 0.01)
+	RecordDBQuery("select", 0.02)
+	RecordDBQuery("insert", 0.03)
+
+	if got := histogramSampleCount(t, "db_query_duration_seconds", "operation", "select"); got != 2 {
+		t.Errorf("select has %d observations, expected 2", got)
+	}
+	if got := histogramSampleCount(t, "db_query_duration_seconds", "operation", "insert"); got != 1 {
+		t.Errorf("insert has %d observations, expected 1", got)
+	}
+}
+
+// histogramSampleCount returns the observation count of the named histogram series whose label matches value
+func histogramSampleCount(t *testing.T, name, label, value string) uint64 {
+	t.Helper()
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather failed: %v", err)
+	}
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, pair := range metric.GetLabel() {
+				if pair.GetName() == label && pair.GetValue() == value {
+					return metric.GetHistogram().GetSampleCount()
+				}
+			}
+		}
+	}
+	return 0
+}
+
+func TestMetricsMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(MetricsMiddleware())
+	router.GET("/orders/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	before := testutil.ToFloat64(RequestCounter.WithLabelValues("GET", "/orders/:id"))
+	for _, path := range []string{"/orders/1", "/orders/2"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+	if got := testutil.ToFloat64(RequestCounter.WithLabelValues("GET", "/orders/:id")) - before; got != 2 {
+		t.Errorf("recorded %v requests for /orders/:id, expected 2", got)
+	}
+	if got := testutil.ToFloat64(RequestCounter.WithLabelValues("GET", unmatchedRoute)); got < 1 {
+		t.Error("unmatched request not recorded")
+	}
+	if n := testutil.CollectAndCount(RequestDuration, "http_request_duration_seconds"); n == 0 {
+		t.Error("request duration not observed")
+	}
+}
+
+func TestMetricsMiddlewareInFlight(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(MetricsMiddleware())
+
+	var during float64
+	router.GET("/inflight", func(c *gin.Context) {
+		during = testutil.ToFloat64(InFlightRequests.WithLabelValues("/inflight"))
+		c.Status(http.StatusOK)
+	})
+	router.GET("/inflight/panic", func(c *gin.Context) { panic("boom") })
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/inflight", nil))
+	func() {
+		defer func() { recover() }()
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/inflight/panic", nil))
+	}()
+
+	if during != 1 {
+		t.Errorf("in-flight gauge was %v while serving, expected 1", during)
+	}
+	if got := testutil.ToFloat64(InFlightRequests.WithLabelValues("/inflight")); got != 0 {
+		t.Errorf("in-flight gauge is %v after the request, expected 0", got)
+	}
+	if got := testutil.ToFloat64(InFlightRequests.WithLabelValues("/inflight/panic")); got != 0 {
+		t.Errorf("in-flight gauge is %v after a panicking request, expected 0", got)
+	}
+}
+
+func TestMetricsMiddlewareStatus(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(MetricsMiddleware())
+	router.GET("/status/fail", func(c *gin.Context) { c.Status(http.StatusInternalServerError) })
+
+	before := testutil.ToFloat64(ResponseStatus.WithLabelValues("GET", "/status/fail", "5xx"))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/status/fail", nil))
+
+	if got := testutil.ToFloat64(ResponseStatus.WithLabelValues("GET", "/status/fail", "5xx")) - before; got != 1 {
+		t.Errorf("5xx bucket increased by %v, expected 1", got)
+	}
+	if got := testutil.ToFloat64(ResponseStatus.WithLabelValues("GET", "/status/fail", "2xx")); got != 0 {
+		t.Errorf("2xx bucket is %v, expected 0", got)
+	}
+}
+
+func TestRecordStatus(t *testing.T) {
+	RecordStatus("POST", "/status/manual", http.StatusNotFound)
+	RecordStatus("POST", "/status/manual", http.StatusCreated)
+
+	if got := testutil.ToFloat64(ResponseStatus.WithLabelValues("POST", "/status/manual", "4xx")); got != 1 {
+		t.Errorf("4xx bucket is %v, expected 1", got)
+	}
+	if got := testutil.ToFloat64(ResponseStatus.WithLabelValues("POST", "/status/manual", "2xx")); got != 1 {
+		t.Errorf("2xx bucket is %v, expected 1", got)
+	}
+}
+
+func TestInitMetricsCustomBuckets(t *testing.T) {
+	defer InitMetrics(nil)
+
+	if err := InitMetrics([]float64{0.5}); err != nil {
+		t.Fatalf("InitMetrics failed: %v", err)
+	}
+	// Re-initializing must replace the collector rather than panic on duplicate registration
+	if err := InitMetrics([]float64{0.001, 0.01, 5}); err != nil {
+		t.Fatalf("second InitMetrics failed: %v", err)
+	}
+
+	RecordDuration("GET", "/buckets", 0.0005)
+	RecordDuration("GET", "/buckets", 0.004)
+	RecordDuration("GET", "/buckets", 2)
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather failed: %v", err)
+	}
+	expected := map[float64]uint64{0.001: 1, 0.01: 2, 5: 3}
+	found := false
+	for _, family := range families {
+		if family.GetName() != "http_request_duration_seconds" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			buckets := metric.GetHistogram().GetBucket()
+			if len(buckets) != len(expected) {
+				t.Fatalf("got %d buckets, expected %d", len(buckets), len(expected))
+			}
+			for _, bucket := range buckets {
+				if bucket.GetCumulativeCount() != expected[bucket.GetUpperBound()] {
+					t.Errorf("bucket le=%v has %d observations, expected %d",
+						bucket.GetUpperBound(), bucket.GetCumulativeCount(), expected[bucket.GetUpperBound()])
+				}
+			}
+			found = true
+		}
+	}
+	if !found {
+		t.Fatal("http_request_duration_seconds not gathered")
+	}
+}
+
+func TestSetServiceName(t *testing.T) {
+	previous, previousRegisterer := registry, registerer
+	defer func() { registry, registerer = previous, previousRegisterer }()
+
+	SetServiceName("order-service")
+	RecordRequest("GET", "/service")
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	RegisterMetricsEndpoint(router)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	expected := `http_requests_total{endpoint="/service",method="GET",service="order-service"}`
+	if !strings.Contains(w.Body.String(), expected) {
+		t.Errorf("/metrics output missing %s", expected)
+	}
+}
+
+func TestRegisterCounter(t *testing.T) {
+	orders := RegisterCounter("orders_placed_total", "Total number of orders placed", []string{"region"})
+	orders.WithLabelValues("eu").Inc()
+
+	again := RegisterCounter("orders_placed_total", "Total number of orders placed", []string{"region"})
+	if again != orders {
+		t.Fatal("re-registration did not return the existing counter")
+	}
+	again.WithLabelValues("eu").Inc()
+	if got := testutil.ToFloat64(orders.WithLabelValues("eu")); got != 2 {
+		t.Errorf("counter is %v, expected 2", got)
+	}
+}
+
+func TestRegisterHistogram(t *testing.T) {
+	latency := RegisterHistogram("payment_latency_seconds", "Payment provider latency", []string{"provider"}, []float64{0.1, 1})
+	latency.WithLabelValues("stripe").Observe(0.5)
+
+	if again := RegisterHistogram("payment_latency_seconds", "Payment provider latency", []string{"provider"}, nil); again != latency {
+		t.Fatal("re-registration did not return the existing histogram")
+	}
+	if got := histogramSampleCount(t, "payment_latency_seconds", "provider", "stripe"); got != 1 {
+		t.Errorf("histogram has %d observations, expected 1", got)
+	}
+}
+
+func TestRecordBuildInfo(t *testing.T) {
+	RecordBuildInfo("0.9.0", "0000000", "2024-01-01")
+	RecordBuildInfo("1.2.3", "abc1234", "2024-05-01")
+
+	expected := `
+# HELP build_info Build information of the running binary
+# TYPE build_info gauge
+build_info{commit="abc1234",date="2024-05-01",version="1.2.3"} 1
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "build_info"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestEnableRuntimeMetrics(t *testing.T) {
+	EnableRuntimeMetrics()
+	EnableRuntimeMetrics()
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather failed: %v", err)
+	}
+	for _, family := range families {
+		if family.GetName() == "go_goroutines" {
+			return
+		}
+	}
+	t.Error("go_goroutines not exported after EnableRuntimeMetrics")
+}
+
+func TestResetMetrics(t *testing.T) {
+	RecordRequest("GET", "/reset")
+	RecordStatus("GET", "/reset", http.StatusOK)
+	business := RegisterCounter("reset_events_total", "Events used by TestResetMetrics", []string{"kind"})
+	business.WithLabelValues("a").Inc()
+
+	ResetMetrics()
+
+	if got := testutil.ToFloat64(RequestCounter.WithLabelValues("GET", "/reset")); got != 0 {
+		t.Errorf("RequestCounter is %v after ResetMetrics, expected 0", got)
+	}
+	if got := testutil.ToFloat64(ResponseStatus.WithLabelValues("GET", "/reset", "2xx")); got != 0 {
+		t.Errorf("ResponseStatus is %v after ResetMetrics, expected 0", got)
+	}
+	if got := testutil.ToFloat64(business.WithLabelValues("a")); got != 0 {
+		t.Errorf("custom counter is %v after ResetMetrics, expected 0", got)
+	}
+}
+
+func TestRegisterMetricsEndpoint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	RegisterMetricsEndpoint(router)
+	RecordRequest("GET", "/test")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /metrics returned %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "http_requests_total") {
+		t.Error("http_requests_total missing from /metrics output")
+	}
+}
+
+func TestPublishCountsFailures(t *testing.T) {
+	before := testutil.ToFloat64(MessagePublishErrors.WithLabelValues("orders.failed"))
+	if err := messaging.Publish("orders.failed", []byte("order-1")); err == nil {
+		t.Fatal("Publish succeeded without a connection")
+	}
+	if got := testutil.ToFloat64(MessagePublishErrors.WithLabelValues("orders.failed")) - before; got != 1 {
+		t.Errorf("publish errors increased by %v, expected 1", got)
+	}
+}
+
+func TestPublishMetricsNotConnected(t *testing.T) {
+	if err := PublishMetrics(map[string]float64{"x": 1}); !errors.Is(err, messaging.ErrNotConnected) {
+		t.Errorf("PublishMetrics returned %v, expected messaging.ErrNotConnected", err)
+	}
+}
+
+func TestPublishMetrics(t *testing.T) {
+	connectTestNATS(t)
+
+	received := make(chan []byte, 1)
+	if _, err := messaging.Subscribe(PublishSubject, func(msg *nats.Msg) { received <- msg.Data }); err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+
+	if err := PublishMetrics(map[string]float64{"orders_processed": 42, "queue_depth": 1.5}); err != nil {
+		t.Fatalf("PublishMetrics failed: %v", err)
+	}
+
+	select {
+	case data := <-received:
+		var got map[string]float64
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatalf("payload is not JSON: %v", err)
+		}
+		if got["orders_processed"] != 42 || got["queue_depth"] != 1.5 {
+			t.Errorf("received %v", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for metrics")
+	}
+}
+
+func TestPublishCountsMessages(t *testing.T) {
+	connectTestNATS(t)
+
+	before := testutil.ToFloat64(MessagesPublished.WithLabelValues("orders.created"))
+	if err := messaging.Publish("orders.created", []byte("order-1")); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	if err := messaging.PublishJSON("orders.created", map[string]string{"id": "order-2"}); err != nil {
+		t.Fatalf("PublishJSON failed: %v", err)
+	}
+	if got := testutil.ToFloat64(MessagesPublished.WithLabelValues("orders.created")) - before; got != 2 {
+		t.Errorf("published counter increased by %v, expected 2", got)
+	}
+}
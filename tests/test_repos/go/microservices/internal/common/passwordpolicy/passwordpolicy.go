@@ -0,0 +1,82 @@
+package passwordpolicy
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Password strength rules enforced by Check, and through it by crypto.CheckPasswordStrength
+// and the validation package's "strongpassword" tag
+var (
+	MinLength     = 8
+	RequireUpper  = true
+	RequireLower  = true
+	RequireDigit  = true
+	RequireSymbol = false
+)
+
+// Check returns an error naming the first strength rule the password fails
+func Check(password string) error {
+	if len([]rune(password)) < MinLength {
+		return fmt.Errorf("password must be at least %d characters long", MinLength)
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			hasSymbol = true
+		}
+	}
+
+	if RequireUpper && !hasUpper {
+		return errors.New("password must contain an uppercase letter")
+	}
+	if RequireLower && !hasLower {
+		return errors.New("password must contain a lowercase letter")
+	}
+	if RequireDigit && !hasDigit {
+		return errors.New("password must contain a digit")
+	}
+	if RequireSymbol && !hasSymbol {
+		return errors.New("password must contain a symbol")
+	}
+	return nil
+}
+
+// Requirements describes the current rules as a phrase completing "password must ...",
+// e.g. "be at least 8 characters long and contain an uppercase letter and a digit"
+func Requirements() string {
+	var classes []string
+	if RequireUpper {
+		classes = append(classes, "an uppercase letter")
+	}
+	if RequireLower {
+		classes = append(classes, "a lowercase letter")
+	}
+	if RequireDigit {
+		classes = append(classes, "a digit")
+	}
+	if RequireSymbol {
+		classes = append(classes, "a symbol")
+	}
+
+	text := fmt.Sprintf("be at least %d characters long", MinLength)
+	switch len(classes) {
+	case 0:
+		return text
+	case 1:
+		return text + " and contain " + classes[0]
+	default:
+		last := len(classes) - 1
+		return text + " and contain " + strings.Join(classes[:last], ", ") + " and " + classes[last]
+	}
+}
@@ -0,0 +1,54 @@
+package passwordpolicy
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	tests := map[string]string{
+		"Ab1":          "characters",
+		"alllower123":  "uppercase",
+		"ALLUPPER123":  "lowercase",
+		"NoDigitsHere": "digit",
+	}
+	for password, rule := range tests {
+		if err := Check(password); err == nil || !strings.Contains(err.Error(), rule) {
+			t.Errorf("Check(%q) = %v, expected an error about %s", password, err, rule)
+		}
+	}
+	if err := Check("Str0ngPassw0rd"); err != nil {
+		t.Errorf("strong password rejected: %v", err)
+	}
+}
+
+func TestCheckFollowsRules(t *testing.T) {
+	previousLength, previousSymbol := MinLength, RequireSymbol
+	defer func() { MinLength, RequireSymbol = previousLength, previousSymbol }()
+	MinLength, RequireSymbol = 12, true
+
+	if err := Check("Str0ngPass"); err == nil {
+		t.Error("Check accepted a password shorter than MinLength")
+	}
+	if err := Check("Str0ngPassw0rd"); err == nil || !strings.Contains(err.Error(), "symbol") {
+		t.Errorf("Check = %v, expected a symbol error with RequireSymbol set", err)
+	}
+	if err := Check("Str0ngPassw0rd!"); err != nil {
+		t.Errorf("password meeting every rule rejected: %v", err)
+	}
+}
+
+func TestRequirements(t *testing.T) {
+	expected := "be at least 8 characters long and contain an uppercase letter, a lowercase letter and a digit"
+	if got := Requirements(); got != expected {
+		t.Errorf("Requirements() = %q, expected %q", got, expected)
+	}
+
+	previousLength, previousUpper, previousLower := MinLength, RequireUpper, RequireLower
+	defer func() { MinLength, RequireUpper, RequireLower = previousLength, previousUpper, previousLower }()
+	MinLength, RequireUpper, RequireLower = 10, false, false
+
+	if got := Requirements(); got != "be at least 10 characters long and contain a digit" {
+		t.Errorf("Requirements() = %q after changing the rules", got)
+	}
+}
@@ -0,0 +1,23 @@
+package testhelper
+
+import (
+	"github.com/greenfuze/go-microservices/internal/common/logger"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"testing"
+)
+
+// NewTestLogger installs a package logger that records every entry, at any level, in memory
+// and returns the recorded entries; the previous logger and level are restored on cleanup
+func NewTestLogger(t testing.TB) *observer.ObservedLogs {
+	t.Helper()
+	core, logs := observer.New(zapcore.DebugLevel)
+	previousLevel := logger.GetLevel()
+	restore := logger.SetLogger(zap.New(core))
+	t.Cleanup(func() {
+		restore()
+		logger.SetLevel(previousLevel)
+	})
+	return logs
+}
@@ -0,0 +1,47 @@
+package testhelper
+
+import (
+	"github.com/greenfuze/go-microservices/internal/common/logger"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"testing"
+)
+
+func TestNewTestLogger(t *testing.T) {
+	logs := NewTestLogger(t)
+
+	logger.Error("payment declined", zap.String("order_id", "order-1"))
+	logger.Debug("retrying")
+
+	entries := logs.FilterMessage("payment declined").All()
+	if len(entries) != 1 {
+		t.Fatalf("recorded %d entries, expected 1", len(entries))
+	}
+	if entries[0].Level != zapcore.ErrorLevel {
+		t.Errorf("recorded at %v, expected error", entries[0].Level)
+	}
+	if entries[0].ContextMap()["order_id"] != "order-1" {
+		t.Error("order_id field missing")
+	}
+	if logs.FilterMessage("retrying").Len() != 1 {
+		t.Error("Debug entry not recorded")
+	}
+}
+
+func TestNewTestLoggerRestoresWithoutDuplicatingGlobalFields(t *testing.T) {
+	logger.SetGlobalFields(zap.String("service", "order-service"))
+	defer logger.SetGlobalFields()
+	before := logger.GetLogger()
+
+	t.Run("scoped", func(t *testing.T) {
+		logs := NewTestLogger(t)
+		logger.Info("inside")
+		if fields := logs.All()[0].Context; len(fields) != 1 {
+			t.Errorf("fields = %v, expected the single global field", fields)
+		}
+	})
+
+	if logger.GetLogger() != before {
+		t.Error("NewTestLogger did not restore the previous logger on cleanup")
+	}
+}
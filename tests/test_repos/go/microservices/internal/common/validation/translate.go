@@ -0,0 +1,90 @@
+package validation
+
+import (
+	"errors"
+	"github.com/go-playground/locales/en"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	entranslations "github.com/go-playground/validator/v10/translations/en"
+	"github.com/greenfuze/go-microservices/internal/common/passwordpolicy"
+	"reflect"
+	"strings"
+)
+
+// translator renders validation errors as English messages
+var translator ut.Translator
+
+func init() {
+	english := en.New()
+	translator, _ = ut.New(english, english).GetTranslator("en")
+	if err := entranslations.RegisterDefaultTranslations(validate, translator); err != nil {
+		panic(err)
+	}
+	if err := RegisterTranslation("username", "{0} must be 3-32 letters, digits or underscores"); err != nil {
+		panic(err)
+	}
+	// Built on each use so the message follows changes to the passwordpolicy rules
+	err := validate.RegisterTranslation("strongpassword", translator,
+		func(ut.Translator) error { return nil },
+		func(_ ut.Translator, fe validator.FieldError) string {
+			return fe.Field() + " must " + passwordpolicy.Requirements()
+		})
+	if err != nil {
+		panic(err)
+	}
+	validate.RegisterTagNameFunc(jsonFieldName)
+}
+
+// jsonFieldName names fields in validation errors after their json tag, falling back to the
+// Go field name when there is none
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+// RegisterTranslation sets the English message TranslateErrors uses for tag, where {0} is
+// replaced by the field name and {1} by the tag parameter
+func RegisterTranslation(tag, message string) error {
+	return validate.RegisterTranslation(tag, translator,
+		func(trans ut.Translator) error {
+			return trans.Add(tag, message, true)
+		},
+		func(trans ut.Translator, fe validator.FieldError) string {
+			text, err := trans.T(tag, fe.Field(), fe.Param())
+			if err != nil {
+				return fe.Error()
+			}
+			return text
+		})
+}
+
+// TranslateErrors converts the validator.ValidationErrors returned by ValidateStruct into
+// English messages keyed by JSON field path (e.g. "email" or "address.city")
+// Any other non-nil error is returned as a single message under the "error" key
+func TranslateErrors(err error) map[string]string {
+	if err == nil {
+		return nil
+	}
+
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return map[string]string{"error": err.Error()}
+	}
+
+	messages := make(map[string]string, len(validationErrs))
+	for _, fe := range validationErrs {
+		messages[fieldPath(fe)] = fe.Translate(translator)
+	}
+	return messages
+}
+
+// fieldPath strips the struct type name from the error's namespace, e.g. "signup.email" becomes "email"
+func fieldPath(fe validator.FieldError) string {
+	if _, path, ok := strings.Cut(fe.Namespace(), "."); ok {
+		return path
+	}
+	return fe.Field()
+}
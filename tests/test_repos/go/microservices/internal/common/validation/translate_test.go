@@ -0,0 +1,48 @@
+package validation
+
+import (
+	"errors"
+	"testing"
+)
+
+type translateAddress struct {
+	City string `json:"city" validate:"required"`
+}
+
+type translateSignup struct {
+	Email    string           `json:"email" validate:"required,email"`
+	Name     string           `json:"name,omitempty" validate:"required"`
+	Username string           `validate:"username"`
+	Address  translateAddress `json:"address"`
+}
+
+func TestTranslateErrors(t *testing.T) {
+	err := ValidateStruct(translateSignup{Email: "not-an-email", Username: "x"})
+	messages := TranslateErrors(err)
+
+	expected := map[string]string{
+		"email":        "email must be a valid email address",
+		"name":         "name is a required field",
+		"Username":     "Username must be 3-32 letters, digits or underscores",
+		"address.city": "city is a required field",
+	}
+	if len(messages) != len(expected) {
+		t.Errorf("TranslateErrors returned %v, expected %v", messages, expected)
+	}
+	for field, message := range expected {
+		if messages[field] != message {
+			t.Errorf("message for %s = %q, expected %q", field, messages[field], message)
+		}
+	}
+}
+
+func TestTranslateErrorsPassesThroughOtherErrors(t *testing.T) {
+	if messages := TranslateErrors(nil); messages != nil {
+		t.Errorf("TranslateErrors(nil) = %v, expected nil", messages)
+	}
+
+	messages := TranslateErrors(errors.New("database unavailable"))
+	if len(messages) != 1 || messages["error"] != "database unavailable" {
+		t.Errorf("TranslateErrors passed through %v, expected the original message under \"error\"", messages)
+	}
+}
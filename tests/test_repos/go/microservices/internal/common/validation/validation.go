@@ -2,12 +2,39 @@ package validation
 
 import (
 	"github.com/go-playground/validator/v10"
+	"github.com/greenfuze/go-microservices/internal/common/passwordpolicy"
+	"regexp"
 )
 
-var validate *validator.Validate
+var validate = validator.New()
+
+// usernamePattern allows letters, digits and underscores, 3 to 32 characters long
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_]{3,32}$`)
 
 func init() {
-	validate = validator.New()
+	if err := RegisterValidation("username", validateUsername); err != nil {
+		panic(err)
+	}
+	if err := RegisterValidation("strongpassword", validateStrongPassword); err != nil {
+		panic(err)
+	}
+}
+
+// RegisterValidation registers fn as the rule for tag on the shared validator, making it
+// usable in validate struct tags and in ValidateStruct
+func RegisterValidation(tag string, fn validator.Func) error {
+	return validate.RegisterValidation(tag, fn)
+}
+
+// validateUsername implements the "username" tag
+func validateUsername(fl validator.FieldLevel) bool {
+	return usernamePattern.MatchString(fl.Field().String())
+}
+
+// validateStrongPassword implements the "strongpassword" tag with passwordpolicy.Check, the
+// same rules as crypto.CheckPasswordStrength
+func validateStrongPassword(fl validator.FieldLevel) bool {
+	return passwordpolicy.Check(fl.Field().String()) == nil
 }
 
 // ValidateStruct validates a struct using go-playground/validator
@@ -15,12 +42,28 @@ func ValidateStruct(s interface{}) error {
 	return validate.Struct(s)
 }
 
+// ValidateField validates value against tag (e.g. "required,email") and returns the
+// validator.ValidationErrors naming the failed rule, or nil when value is valid
+func ValidateField(value interface{}, tag string) error {
+	return validate.Var(value, tag)
+}
+
 // ValidateEmail validates an email address
 func ValidateEmail(email string) bool {
-	return validate.Var(email, "required,email") == nil
+	return ValidateField(email, "required,email") == nil
 }
 
 // ValidateRequired validates that a field is not empty
 func ValidateRequired(field string) bool {
-	return validate.Var(field, "required") == nil
+	return ValidateField(field, "required") == nil
+}
+
+// ValidateUUID validates a lowercase version 4 UUID such as uuid.New().String()
+func ValidateUUID(s string) bool {
+	return ValidateField(s, "required,uuid4") == nil
+}
+
+// ValidatePhone validates a phone number in E.164 format, e.g. +14155552671
+func ValidatePhone(s string) bool {
+	return ValidateField(s, "required,e164") == nil
 }
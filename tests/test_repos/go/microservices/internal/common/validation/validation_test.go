@@ -1,6 +1,30 @@
 package validation
 
-import "testing"
+import (
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/greenfuze/go-microservices/internal/common/passwordpolicy"
+	"strings"
+	"testing"
+)
+
+func TestValidateField(t *testing.T) {
+	if err := ValidateField("test@example.com", "email"); err != nil {
+		t.Errorf("Valid email failed validation: %v", err)
+	}
+
+	err := ValidateField("bad", "email")
+	if err == nil {
+		t.Fatal("Invalid email passed validation")
+	}
+	if !strings.Contains(err.Error(), "email") {
+		t.Errorf("error %q does not mention the email tag", err)
+	}
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) || validationErrs[0].Tag() != "email" {
+		t.Errorf("error %v does not report the failed email rule", err)
+	}
+}
 
 func TestValidateEmail(t *testing.T) {
 	if !ValidateEmail("test@example.com") {
@@ -19,3 +43,128 @@ func TestValidateRequired(t *testing.T) {
 		t.Error("Empty field passed validation")
 	}
 }
+
+func TestValidateUUID(t *testing.T) {
+	for _, id := range []string{"3f1c9a52-8b7e-4d2a-9c4f-1e2d3c4b5a69", "00000000-0000-4000-8000-000000000000"} {
+		if !ValidateUUID(id) {
+			t.Errorf("Valid UUID %q failed validation", id)
+		}
+	}
+	invalid := []string{
+		"",
+		"not-a-uuid",
+		"3f1c9a528b7e4d2a9c4f1e2d3c4b5a69",     // no hyphens
+		"3f1c9a52-8b7e-1d2a-9c4f-1e2d3c4b5a69", // version 1
+		"3f1c9a52-8b7e-4d2a-7c4f-1e2d3c4b5a69", // invalid variant
+		"3f1c9a52-8b7e-4d2a-9c4f-1e2d3c4b5a6",  // too short
+		"3F1C9A52-8B7E-4D2A-9C4F-1E2D3C4B5A69", // uppercase, which uuid4 rejects
+	}
+	for _, id := range invalid {
+		if ValidateUUID(id) {
+			t.Errorf("Invalid UUID %q passed validation", id)
+		}
+	}
+}
+
+func TestValidatePhone(t *testing.T) {
+	for _, phone := range []string{"+14155552671", "+442071838750", "+49301234567"} {
+		if !ValidatePhone(phone) {
+			t.Errorf("Valid phone %q failed validation", phone)
+		}
+	}
+	invalid := []string{
+		"",
+		"14155552671",       // missing +
+		"+1 415 555 2671",   // separators
+		"+1415555267123456", // longer than 15 digits
+	}
+	for _, phone := range invalid {
+		if ValidatePhone(phone) {
+			t.Errorf("Invalid phone %q passed validation", phone)
+		}
+	}
+}
+
+func TestUsernameValidation(t *testing.T) {
+	type signup struct {
+		Username string `validate:"username"`
+	}
+
+	for _, username := range []string{"bob", "alice_99", "ABCDEFGHIJKLMNOPQRSTUVWXYZ_01234"} {
+		if err := ValidateStruct(signup{Username: username}); err != nil {
+			t.Errorf("Valid username %q failed validation: %v", username, err)
+		}
+	}
+	for _, username := range []string{"", "ab", "has space", "dash-name", "ABCDEFGHIJKLMNOPQRSTUVWXYZ_012345"} {
+		if err := ValidateStruct(signup{Username: username}); err == nil {
+			t.Errorf("Invalid username %q passed validation", username)
+		}
+	}
+}
+
+func TestRegisterValidation(t *testing.T) {
+	// Registrations cannot be removed, so register on a fresh validator instead of the shared one
+	previous := validate
+	validate = validator.New()
+	t.Cleanup(func() { validate = previous })
+
+	err := RegisterValidation("even", func(fl validator.FieldLevel) bool {
+		return fl.Field().Int()%2 == 0
+	})
+	if err != nil {
+		t.Fatalf("RegisterValidation failed: %v", err)
+	}
+
+	type batch struct {
+		Size int `validate:"even"`
+	}
+	if err := ValidateStruct(batch{Size: 4}); err != nil {
+		t.Errorf("Even size failed validation: %v", err)
+	}
+	if err := ValidateStruct(batch{Size: 3}); err == nil {
+		t.Error("Odd size passed validation")
+	}
+}
+
+func TestStrongPasswordValidation(t *testing.T) {
+	type signup struct {
+		Password string `validate:"strongpassword"`
+	}
+
+	if err := ValidateStruct(signup{Password: "Str0ngPass"}); err != nil {
+		t.Errorf("Strong password failed validation: %v", err)
+	}
+	weak := []string{
+		"",
+		"Sh0rt",       // under 8 characters
+		"alllower123", // no uppercase
+		"ALLUPPER123", // no lowercase
+		"NoDigitsHere",
+	}
+	for _, password := range weak {
+		if err := ValidateStruct(signup{Password: password}); err == nil {
+			t.Errorf("Weak password %q passed validation", password)
+		}
+	}
+}
+
+func TestStrongPasswordFollowsPolicy(t *testing.T) {
+	type signup struct {
+		Password string `json:"password" validate:"strongpassword"`
+	}
+	previous := passwordpolicy.RequireSymbol
+	passwordpolicy.RequireSymbol = true
+	defer func() { passwordpolicy.RequireSymbol = previous }()
+
+	err := ValidateStruct(signup{Password: "Str0ngPass"})
+	if err == nil {
+		t.Fatal("Password without a symbol passed validation while the policy requires one")
+	}
+	expected := "password must " + passwordpolicy.Requirements()
+	if message := TranslateErrors(err)["password"]; message != expected || !strings.Contains(message, "symbol") {
+		t.Errorf("message = %q, expected %q", message, expected)
+	}
+	if err := ValidateStruct(signup{Password: "Str0ngPass!"}); err != nil {
+		t.Errorf("Password meeting the policy failed validation: %v", err)
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"errors"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"github.com/greenfuze/go-microservices/internal/common/config"
+	"strings"
+	"time"
 )
 
 // Claims represents JWT claims
@@ -14,13 +17,40 @@ type Claims struct {
 
 // GenerateToken generates a JWT token
 func GenerateToken(userID uuid.UUID, secret string) (string, error) {
+	return generateToken(userID, secret, 0)
+}
+
+// GenerateTokenFromConfig generates a JWT token using the secret and TTL from the loaded configuration
+func GenerateTokenFromConfig(userID uuid.UUID) (string, error) {
+	jwtConfig, err := getJWTConfig()
+	if err != nil {
+		return "", err
+	}
+	return generateToken(userID, jwtConfig.Secret, jwtConfig.AccessTTL)
+}
+
+func generateToken(userID uuid.UUID, secret string, ttl time.Duration) (string, error) {
 	claims := &Claims{
 		UserID: userID,
 	}
+	if ttl > 0 {
+		claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(ttl))
+	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(secret))
 }
 
+func getJWTConfig() (config.JWTConfig, error) {
+	cfg := config.GetConfig()
+	if cfg == nil {
+		return config.JWTConfig{}, errors.New("config not loaded")
+	}
+	if cfg.JWT.Secret == "" {
+		return config.JWTConfig{}, errors.New("jwt secret not configured")
+	}
+	return cfg.JWT, nil
+}
+
 // ValidateToken validates a JWT token
 func ValidateToken(tokenString, secret string) (*Claims, error) {
 	claims := &Claims{}
@@ -35,3 +65,29 @@ func ValidateToken(tokenString, secret string) (*Claims, error) {
 	}
 	return claims, nil
 }
+
+// ValidateTokenFromConfig validates a JWT token using the secret from the loaded configuration
+func ValidateTokenFromConfig(tokenString string) (*Claims, error) {
+	jwtConfig, err := getJWTConfig()
+	if err != nil {
+		return nil, err
+	}
+	return ValidateToken(tokenString, jwtConfig.Secret)
+}
+
+// ExtractBearerToken extracts the token from an "Authorization: Bearer <token>" header value
+func ExtractBearerToken(header string) (string, error) {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return "", errors.New("authorization header is missing")
+	}
+	const prefix = "bearer "
+	if len(header) < len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return "", errors.New("authorization header is not a bearer token")
+	}
+	token := strings.TrimSpace(header[len(prefix):])
+	if token == "" || strings.ContainsAny(token, " \t") {
+		return "", errors.New("authorization header is malformed")
+	}
+	return token, nil
+}
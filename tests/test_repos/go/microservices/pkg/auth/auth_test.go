@@ -1,8 +1,9 @@
 package auth
 
 import (
-	"testing"
 	"github.com/google/uuid"
+	"github.com/greenfuze/go-microservices/internal/common/config"
+	"testing"
 )
 
 func TestGenerateToken(t *testing.T) {
@@ -15,3 +16,53 @@ func TestGenerateToken(t *testing.T) {
 		t.Error("GenerateToken returned empty token")
 	}
 }
+
+func TestExtractBearerToken(t *testing.T) {
+	token, err := ExtractBearerToken("Bearer abc.def.ghi")
+	if err != nil {
+		t.Fatalf("ExtractBearerToken failed: %v", err)
+	}
+	if token != "abc.def.ghi" {
+		t.Errorf("ExtractBearerToken returned %q", token)
+	}
+
+	token, err = ExtractBearerToken("  bearer   abc.def.ghi  ")
+	if err != nil {
+		t.Fatalf("ExtractBearerToken failed with extra whitespace: %v", err)
+	}
+	if token != "abc.def.ghi" {
+		t.Errorf("ExtractBearerToken returned %q with extra whitespace", token)
+	}
+}
+
+func TestExtractBearerTokenInvalid(t *testing.T) {
+	for _, header := range []string{"", "   ", "Basic dXNlcjpwYXNz", "Bearer", "Bearer    ", "Bearer abc def"} {
+		if _, err := ExtractBearerToken(header); err == nil {
+			t.Errorf("ExtractBearerToken(%q) succeeded, expected error", header)
+		}
+	}
+}
+
+func TestGenerateTokenFromConfig(t *testing.T) {
+	t.Setenv("JWT_SECRET", "config-secret")
+	if _, err := config.LoadConfig(); err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	userID := uuid.New()
+	token, err := GenerateTokenFromConfig(userID)
+	if err != nil {
+		t.Fatalf("GenerateTokenFromConfig failed: %v", err)
+	}
+
+	claims, err := ValidateTokenFromConfig(token)
+	if err != nil {
+		t.Fatalf("ValidateTokenFromConfig failed: %v", err)
+	}
+	if claims.UserID != userID {
+		t.Errorf("claims.UserID = %v, expected %v", claims.UserID, userID)
+	}
+	if claims.ExpiresAt == nil {
+		t.Error("token generated from config has no expiry")
+	}
+}